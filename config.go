@@ -0,0 +1,157 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+
+	"backend-projects/article-api/handlers"
+)
+
+// missingEnv returns those of keys whose environment variable is unset or
+// empty.
+func missingEnv(keys ...string) []string {
+	var missing []string
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// envString returns the environment variable key, or fallback when it is
+// unset.
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envInt returns the integer value of the environment variable key, or
+// fallback when it is unset.
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+// envDuration returns the duration value of the environment variable key, or
+// fallback when it is unset.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+// envFloat returns the floating point value of the environment variable key,
+// or fallback when it is unset.
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+// envList splits the comma-separated environment variable key into its
+// non-empty items, or returns fallback when it is unset.
+func envList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envBool returns the boolean value of the environment variable key, or
+// fallback when it is unset.
+func envBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return parsed
+}
+
+// newLogger builds the logger from LOG_LEVEL and LOG_FORMAT. They default to
+// JSON at info level in production and to text at debug level otherwise.
+func newLogger(production bool) *slog.Logger {
+	level, format := "debug", "text"
+	if production {
+		level, format = "info", "json"
+	}
+	var options slog.HandlerOptions
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(envString("LOG_LEVEL", level))); err != nil {
+		log.Fatalf("invalid LOG_LEVEL: %v", err)
+	}
+	options.Level = logLevel
+
+	switch format = envString("LOG_FORMAT", format); format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, &options))
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, &options))
+	default:
+		log.Fatalf("invalid LOG_FORMAT %q: must be json or text", format)
+		return nil
+	}
+}
+
+// corsConfig reads the CORS policy from the environment. Only the local
+// frontend is allowed by default; allowing every origin requires setting
+// CORS_ALLOWED_ORIGINS=* explicitly, and cannot be combined with credentials.
+func corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowMethods = envList("CORS_ALLOWED_METHODS", config.AllowMethods)
+	config.AllowHeaders = envList("CORS_ALLOWED_HEADERS", append(config.AllowHeaders, "Authorization", handlers.RequestIDHeader, handlers.IdempotencyKeyHeader, handlers.APIKeyHeader))
+	config.ExposeHeaders = []string{handlers.RequestIDHeader, "Link", "Location", "ETag", "Retry-After"}
+	config.AllowCredentials = envBool("CORS_ALLOW_CREDENTIALS", false)
+	config.MaxAge = envDuration("CORS_MAX_AGE", config.MaxAge)
+
+	origins := envList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"})
+	if slices.Contains(origins, "*") {
+		if config.AllowCredentials {
+			log.Fatal("CORS_ALLOWED_ORIGINS=* cannot be combined with CORS_ALLOW_CREDENTIALS")
+		}
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = origins
+	}
+
+	return config
+}
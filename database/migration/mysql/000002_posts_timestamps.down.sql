@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    MODIFY created_date TIMESTAMP DEFAULT NOW(),
+    MODIFY updated_date TIMESTAMP DEFAULT NOW();
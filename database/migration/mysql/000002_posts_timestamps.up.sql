@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    MODIFY created_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    MODIFY updated_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN slug VARCHAR(255) NULL AFTER title;
+-- Existing rows get a placeholder slug; new posts derive theirs from the title.
+UPDATE posts SET slug = CONCAT('article-', id) WHERE slug IS NULL;
+ALTER TABLE posts
+    MODIFY slug VARCHAR(255) NOT NULL,
+    ADD UNIQUE INDEX posts_slug_unique (slug);
@@ -0,0 +1 @@
+ALTER TABLE posts DROP INDEX posts_author_index, DROP COLUMN author;
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ADD COLUMN author VARCHAR(100) NOT NULL DEFAULT '' AFTER category,
+    ADD INDEX posts_author_index (author);
@@ -0,0 +1,2 @@
+ALTER TABLE posts DROP FOREIGN KEY posts_category_fk, DROP COLUMN category_id;
+DROP TABLE IF EXISTS categories;
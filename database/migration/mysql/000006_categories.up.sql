@@ -0,0 +1,12 @@
+CREATE TABLE categories (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX categories_name_unique (name)
+);
+
+-- posts.category stays the source of the name for posts that predate
+-- categories; category_id is filled in when a post is linked to one.
+ALTER TABLE posts
+    ADD COLUMN category_id INT NULL AFTER category,
+    ADD CONSTRAINT posts_category_fk FOREIGN KEY (category_id) REFERENCES categories (id);
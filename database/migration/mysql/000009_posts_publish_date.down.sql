@@ -0,0 +1 @@
+ALTER TABLE posts DROP INDEX posts_status_publish_date, DROP COLUMN publish_date;
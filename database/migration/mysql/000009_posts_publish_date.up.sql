@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ADD COLUMN publish_date TIMESTAMP NULL AFTER status,
+    ADD INDEX posts_status_publish_date (status, publish_date);
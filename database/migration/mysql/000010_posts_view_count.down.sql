@@ -0,0 +1 @@
+ALTER TABLE posts DROP INDEX posts_view_count_index, DROP COLUMN view_count;
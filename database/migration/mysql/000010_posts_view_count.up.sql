@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ADD COLUMN view_count INT NOT NULL DEFAULT 0 AFTER version,
+    ADD INDEX posts_view_count_index (view_count);
@@ -0,0 +1,16 @@
+-- post_revisions keeps the state a post had before each edit, keyed by the
+-- version that state carried.
+CREATE TABLE post_revisions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    post_id INT NOT NULL,
+    version INT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL,
+    excerpt VARCHAR(500) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    category_id INT NULL,
+    status VARCHAR(10) NOT NULL,
+    created_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX post_revisions_post_version (post_id, version),
+    CONSTRAINT post_revisions_post_fk FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE posts DROP COLUMN cover_image_url;
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN cover_image_url VARCHAR(2048) NOT NULL DEFAULT '' AFTER excerpt;
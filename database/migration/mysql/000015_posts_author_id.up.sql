@@ -0,0 +1,3 @@
+-- author_id is the subject of the credentials that created the post, which
+-- decides who may edit it. Posts created before authentication have none.
+ALTER TABLE posts ADD COLUMN author_id VARCHAR(255) NULL AFTER author;
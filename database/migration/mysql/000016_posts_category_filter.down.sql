@@ -0,0 +1 @@
+ALTER TABLE posts MODIFY category VARCHAR(100) NOT NULL;
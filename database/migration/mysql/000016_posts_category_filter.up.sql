@@ -0,0 +1,4 @@
+-- The category filter ignores case and accents, which needs a collation
+-- that does too. utf8mb4_unicode_ci is one on both MySQL and MariaDB,
+-- whatever the server's default.
+ALTER TABLE posts MODIFY category VARCHAR(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE posts DROP INDEX posts_content_hash_index, DROP COLUMN content_hash;
@@ -0,0 +1,6 @@
+-- content_hash is the SHA-256 of a post's content with markup, case and
+-- spacing ignored, so duplicates can be found without comparing content. It
+-- is NULL until the API has hashed the post's content.
+ALTER TABLE posts
+    ADD COLUMN content_hash CHAR(64) NULL AFTER word_count,
+    ADD INDEX posts_content_hash_index (content_hash);
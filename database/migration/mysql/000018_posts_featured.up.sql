@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ADD COLUMN featured BOOLEAN NOT NULL DEFAULT FALSE AFTER status,
+    ADD INDEX posts_featured_index (featured);
@@ -0,0 +1 @@
+ALTER TABLE posts DROP INDEX posts_like_count_index, DROP COLUMN like_count;
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ADD COLUMN like_count INT NOT NULL DEFAULT 0 AFTER view_count,
+    ADD INDEX posts_like_count_index (like_count);
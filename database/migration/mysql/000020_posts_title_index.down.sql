@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    DROP INDEX posts_title_index,
+    MODIFY title VARCHAR(200) NOT NULL;
@@ -0,0 +1,5 @@
+-- Title suggestions match prefixes through this index with a plain LIKE,
+-- which needs a collation that ignores case whatever the server's default.
+ALTER TABLE posts
+    MODIFY title VARCHAR(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+    ADD INDEX posts_title_index (title);
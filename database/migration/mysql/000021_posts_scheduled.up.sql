@@ -0,0 +1,4 @@
+-- scheduled marks a published post whose publish_date is still ahead, so the
+-- scheduler can announce it once it goes live.
+ALTER TABLE posts ADD COLUMN scheduled BOOLEAN NOT NULL DEFAULT FALSE AFTER publish_date;
+UPDATE posts SET scheduled = (status = 'publish' AND publish_date IS NOT NULL AND publish_date > CURRENT_TIMESTAMP);
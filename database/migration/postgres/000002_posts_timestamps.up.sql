@@ -0,0 +1,3 @@
+ALTER TABLE posts
+    ALTER COLUMN created_date SET NOT NULL,
+    ALTER COLUMN updated_date SET NOT NULL;
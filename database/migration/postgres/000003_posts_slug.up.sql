@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN slug VARCHAR(255) NULL;
+-- Existing rows get a placeholder slug; new posts derive theirs from the title.
+UPDATE posts SET slug = 'article-' || id WHERE slug IS NULL;
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX posts_slug_unique ON posts (slug);
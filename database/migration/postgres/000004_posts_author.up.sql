@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN author VARCHAR(100) NOT NULL DEFAULT '';
+CREATE INDEX posts_author_index ON posts (author);
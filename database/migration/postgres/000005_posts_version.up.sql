@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT categories_name_unique UNIQUE (name)
+);
+
+-- posts.category stays the source of the name for posts that predate
+-- categories; category_id is filled in when a post is linked to one.
+ALTER TABLE posts
+    ADD COLUMN category_id INT NULL,
+    ADD CONSTRAINT posts_category_fk FOREIGN KEY (category_id) REFERENCES categories (id);
@@ -0,0 +1,12 @@
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    CONSTRAINT tags_name_unique UNIQUE (name)
+);
+
+CREATE TABLE post_tags (
+    post_id INT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX post_tags_tag_id ON post_tags (tag_id);
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN excerpt VARCHAR(500) NOT NULL DEFAULT '';
+
+-- Existing posts get a rough excerpt; the API regenerates a cleaner one the
+-- next time a post is replaced with an empty excerpt.
+UPDATE posts SET excerpt = LEFT(content, 160);
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN publish_date TIMESTAMPTZ NULL;
+CREATE INDEX posts_status_publish_date ON posts (status, publish_date);
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN view_count INT NOT NULL DEFAULT 0;
+CREATE INDEX posts_view_count_index ON posts (view_count);
@@ -0,0 +1,15 @@
+-- post_revisions keeps the state a post had before each edit, keyed by the
+-- version that state carried.
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    content TEXT NOT NULL,
+    excerpt VARCHAR(500) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    category_id INT NULL,
+    status VARCHAR(10) NOT NULL,
+    created_date TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT post_revisions_post_version UNIQUE (post_id, version)
+);
@@ -0,0 +1,4 @@
+-- format says how content is written: html, sanitized when stored, or
+-- markdown, kept as written and rendered on request.
+ALTER TABLE posts ADD COLUMN format VARCHAR(10) NOT NULL DEFAULT 'html';
+ALTER TABLE post_revisions ADD COLUMN format VARCHAR(10) NOT NULL DEFAULT 'html';
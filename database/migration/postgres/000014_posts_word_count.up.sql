@@ -0,0 +1,3 @@
+-- word_count lets statistics sum words without reading all content. It is
+-- NULL until the API has counted the post's words.
+ALTER TABLE posts ADD COLUMN word_count INT NULL;
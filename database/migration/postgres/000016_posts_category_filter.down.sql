@@ -0,0 +1 @@
+DROP INDEX posts_category_lower_idx;
@@ -0,0 +1,3 @@
+-- The category filter compares LOWER(category), which this index serves.
+-- PostgreSQL folds case only; accents still have to match.
+CREATE INDEX posts_category_lower_idx ON posts (LOWER(category));
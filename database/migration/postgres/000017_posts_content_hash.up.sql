@@ -0,0 +1,5 @@
+-- content_hash is the SHA-256 of a post's content with markup, case and
+-- spacing ignored, so duplicates can be found without comparing content. It
+-- is NULL until the API has hashed the post's content.
+ALTER TABLE posts ADD COLUMN content_hash CHAR(64) NULL;
+CREATE INDEX posts_content_hash_index ON posts (content_hash);
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN featured BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX posts_featured_index ON posts (featured);
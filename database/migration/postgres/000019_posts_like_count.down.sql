@@ -0,0 +1 @@
+ALTER TABLE posts DROP COLUMN like_count;
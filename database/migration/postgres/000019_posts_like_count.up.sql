@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN like_count INT NOT NULL DEFAULT 0;
+CREATE INDEX posts_like_count_index ON posts (like_count);
@@ -0,0 +1 @@
+DROP INDEX posts_title_lower_idx;
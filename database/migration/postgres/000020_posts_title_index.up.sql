@@ -0,0 +1,3 @@
+-- Title suggestions match prefixes of LOWER(title) with LIKE, which only an
+-- index with the pattern operator class can serve.
+CREATE INDEX posts_title_lower_idx ON posts (LOWER(title) text_pattern_ops);
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"backend-projects/article-api/model"
+)
+
+// claimsKey is the gin context key Authenticate stores a request's token
+// claims under.
+const claimsKey = "claims"
+
+// apiKeySubject is the subject of the claims stored for a request made with
+// an API key.
+const apiKeySubject = "api-key"
+
+// Roles carried in token claims. Admins may do anything; authors may create
+// articles and change their own. API keys act as admins.
+const (
+	RoleAdmin  = "admin"
+	RoleAuthor = "author"
+)
+
+// authClaims are the claims read from a bearer token.
+type authClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+// requestClaims returns the claims Authenticate stored for the request, or
+// nil when it carried no credentials.
+func requestClaims(context *gin.Context) *authClaims {
+	claims, _ := context.Get(claimsKey)
+	authenticated, _ := claims.(*authClaims)
+	return authenticated
+}
+
+// RequireRole lets a request through only when its credentials carry one of
+// roles, answering 401 without credentials and 403 with the wrong role.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		claims := requestClaims(context)
+		if claims == nil {
+			context.Header("WWW-Authenticate", "Bearer")
+			respondError(context, http.StatusUnauthorized, "a bearer token or API key is required")
+			context.Abort()
+			return
+		}
+		if !slices.Contains(roles, claims.Role) {
+			respondError(context, http.StatusForbidden, "your role may not do this")
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
+
+// canEdit reports whether the request's credentials may change post: admins
+// may change any post, authors only those they created. It answers 403
+// itself, returning false, otherwise.
+func canEdit(context *gin.Context, post model.Post) bool {
+	claims := requestClaims(context)
+	if claims != nil && (claims.Role == RoleAdmin || claims.Role == RoleAuthor && post.AuthorID != nil && *post.AuthorID == claims.Subject) {
+		return true
+	}
+	respondError(context, http.StatusForbidden, "authors may only change their own articles")
+	return false
+}
+
+// Authenticate checks the credentials a request carries, answering 401 when
+// they are invalid: either an X-API-Key header holding one of apiKeys, or an
+// HS256 bearer token signed with secret. A nil secret or empty apiKeys turns
+// that kind of credential off. Credentials are required on every request
+// that can change data; reads stay public, as do counting a view and liking
+// or unliking, which are anonymous counts rather than edits. The token's claims, or admin claims with apiKeySubject for
+// an API key, are stored in the context under claimsKey.
+func Authenticate(secret []byte, apiKeys []string) gin.HandlerFunc {
+	// Comparing digests keeps the comparison constant-time even in the
+	// length of the keys.
+	keyDigests := make([][sha256.Size]byte, len(apiKeys))
+	for i, key := range apiKeys {
+		keyDigests[i] = sha256.Sum256([]byte(key))
+	}
+
+	return func(context *gin.Context) {
+		method := context.Request.Method
+		path := context.FullPath()
+		public := method == http.MethodGet || method == http.MethodHead || path == "/article/:id/view" || path == "/article/:id/like"
+		_, hasToken := strings.CutPrefix(context.GetHeader("Authorization"), "Bearer ")
+		if public && !hasToken && context.GetHeader(APIKeyHeader) == "" {
+			context.Next()
+			return
+		}
+
+		if key := context.GetHeader(APIKeyHeader); key != "" {
+			digest := sha256.Sum256([]byte(key))
+			matched := 0
+			for _, keyDigest := range keyDigests {
+				matched |= subtle.ConstantTimeCompare(digest[:], keyDigest[:])
+			}
+			if matched == 0 {
+				slog.Warn("rejected API key", "key_prefix", key[:min(len(key)/2, 4)], "client_ip", context.ClientIP())
+				respondError(context, http.StatusUnauthorized, "invalid API key")
+				context.Abort()
+				return
+			}
+			context.Set(claimsKey, &authClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: apiKeySubject}, Role: RoleAdmin})
+			context.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(context.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || secret == nil {
+			context.Header("WWW-Authenticate", "Bearer")
+			respondError(context, http.StatusUnauthorized, "a bearer token or API key is required")
+			context.Abort()
+			return
+		}
+
+		claims := &authClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+		if err != nil {
+			context.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondError(context, http.StatusUnauthorized, "invalid bearer token")
+			context.Abort()
+			return
+		}
+
+		context.Set(claimsKey, claims)
+		context.Next()
+	}
+}
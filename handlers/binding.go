@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"backend-projects/article-api/model"
+)
+
+// normalizer is implemented by request bodies that normalizedBinding cleans
+// up before validating them.
+type normalizer interface {
+	Normalize()
+}
+
+// normalizedBinding decodes a JSON body like binding.JSON but normalizes it
+// before running the binding tag rules, so that validation and storage both
+// see the same values.
+var normalizedBinding binding.BindingBody = normalizedJSON{}
+
+type normalizedJSON struct{}
+
+func (normalizedJSON) Name() string { return "json" }
+
+func (b normalizedJSON) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return b.BindBody(body, obj)
+}
+
+func (normalizedJSON) BindBody(body []byte, obj any) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errEmptyBody
+	}
+	if err := json.Unmarshal(body, obj); err != nil {
+		return err
+	}
+	if n, ok := obj.(normalizer); ok {
+		n.Normalize()
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// errEmptyBody is returned by normalizedBinding for a body with nothing in it.
+var errEmptyBody = errors.New("body is empty")
+
+// decodeMessage explains why what, such as "request body", could not be
+// decoded, without the decoder's own wording, which names Go types and byte
+// offsets. It returns "" when err is not a decoding error.
+func decodeMessage(err error, what string) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.Is(err, errEmptyBody) || errors.Is(err, io.EOF):
+		return what + " is empty"
+	case errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF):
+		return what + " is not valid JSON"
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return what + " must be a JSON " + jsonType(typeErr.Type)
+	case errors.As(err, &typeErr) && jsonType(typeErr.Type) == "time":
+		return typeErr.Field + " must be a string " + rfc3339Hint
+	case errors.As(err, &typeErr):
+		return typeErr.Field + " must be a JSON " + jsonType(typeErr.Type)
+	case errors.As(err, &timeErr):
+		return "times must be " + rfc3339Hint
+	default:
+		return ""
+	}
+}
+
+// rfc3339Hint tells clients how to write a time.
+const rfc3339Hint = "in RFC 3339 format, such as 2024-01-02T15:04:05Z"
+
+// jsonType names the kind of JSON value that decodes into t, or is "time"
+// for a time.Time, which is written as a string of its own format.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return "time"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// RegisterValidators adds the custom rules used in binding tags to gin's
+// validator, and makes it report fields by their JSON names.
+func RegisterValidators() error {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// minrunes=n requires at least n characters rather than bytes.
+	if err := validate.RegisterValidation("minrunes", func(fl validator.FieldLevel) bool {
+		minimum, err := strconv.Atoi(fl.Param())
+		return err == nil && utf8.RuneCountInString(fl.Field().String()) >= minimum
+	}); err != nil {
+		return err
+	}
+	if err := validate.RegisterValidation("image_url", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "" || model.IsImageURL(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+	if err := validate.RegisterValidation("no_banned_terms", func(fl validator.FieldLevel) bool {
+		return model.BannedTerms == nil || len(model.BannedTerms.Find(fl.Field().String())) == 0
+	}); err != nil {
+		return err
+	}
+	return validate.RegisterValidation("post_status", func(fl validator.FieldLevel) bool {
+		return model.IsValidStatus(fl.Field().String())
+	})
+}
+
+// fieldErrors describes each failed rule in errs in words.
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, len(errs))
+	for i, err := range errs {
+		name := strings.ReplaceAll(err.Field(), "_", " ")
+		name = strings.ToUpper(name[:1]) + name[1:]
+
+		var message string
+		switch err.Tag() {
+		case "required", "required_without":
+			message = name + " is required"
+		case "minrunes":
+			message = fmt.Sprintf("%s must be at least %s characters", name, err.Param())
+		case "max":
+			if err.Kind() == reflect.Slice {
+				message = fmt.Sprintf("%s must have at most %s items", name, err.Param())
+			} else {
+				message = fmt.Sprintf("%s must be at most %s characters", name, err.Param())
+			}
+		case "oneof":
+			message = fmt.Sprintf("%s must be one of %s", name, strings.ReplaceAll(err.Param(), " ", ", "))
+		case "image_url":
+			message = name + " must be an http or https link to an image"
+		case "post_status":
+			message = name + " must be either publish, draft, or trash"
+		case "no_banned_terms":
+			message = name + " contains blocked terms: " + strings.Join(model.BannedTerms.Find(err.Value().(string)), ", ")
+		default:
+			message = name + " is invalid"
+		}
+		fields[i] = FieldError{Field: err.Field(), Message: message}
+	}
+	return fields
+}
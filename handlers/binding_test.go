@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"backend-projects/article-api/model"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := RegisterValidators(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// validPost returns a post that passes every binding rule.
+func validPost() model.Post {
+	return model.Post{
+		Title:    "A title long enough to pass",
+		Content:  strings.Repeat("Some words of content. ", 10),
+		Category: "testing",
+		Author:   "Tester",
+		Status:   "draft",
+	}
+}
+
+// bindPost runs post through the binding used for POST and PUT bodies and
+// returns the tags of the rules that failed on field, along with the post as
+// bound.
+func bindPost(t *testing.T, post model.Post, field string) ([]string, model.Post) {
+	t.Helper()
+	body, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bound model.Post
+	err = normalizedBinding.BindBody(body, &bound)
+	var errs validator.ValidationErrors
+	if err != nil && !errors.As(err, &errs) {
+		t.Fatalf("BindBody: %v", err)
+	}
+	var failed []string
+	for _, fieldErr := range errs {
+		if fieldErr.Field() == field {
+			failed = append(failed, fieldErr.Tag())
+		}
+	}
+	return failed, bound
+}
+
+func TestMinRunesCountsCharacters(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		set     func(*model.Post, string)
+		value   string
+		wantTag string
+	}{
+		{"title of 20 two-byte characters", "title", setTitle, strings.Repeat("é", 20), ""},
+		{"title of 19 three-byte characters", "title", setTitle, strings.Repeat("日", 19), "minrunes"},
+		{"title of 20 three-byte characters", "title", setTitle, strings.Repeat("日", 20), ""},
+		{"title of 19 four-byte characters", "title", setTitle, strings.Repeat("😀", 19), "minrunes"},
+		{"title of 200 three-byte characters", "title", setTitle, strings.Repeat("日", 200), ""},
+		{"title of 201 three-byte characters", "title", setTitle, strings.Repeat("日", 201), "max"},
+		{"content of 200 three-byte characters", "content", setContent, strings.Repeat("本", 200), ""},
+		{"content of 199 three-byte characters", "content", setContent, strings.Repeat("本", 199), "minrunes"},
+		{"category of 3 two-byte characters", "category", setCategory, "ççç", ""},
+		{"category of 2 three-byte characters", "category", setCategory, "日本", "minrunes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			post := validPost()
+			test.set(&post, test.value)
+
+			failed, _ := bindPost(t, post, test.field)
+			if test.wantTag == "" && len(failed) > 0 {
+				t.Errorf("%s failed %v, want valid", test.field, failed)
+			}
+			if test.wantTag != "" && (len(failed) != 1 || failed[0] != test.wantTag) {
+				t.Errorf("%s failed %v, want [%s]", test.field, failed, test.wantTag)
+			}
+		})
+	}
+}
+
+func setTitle(post *model.Post, value string)    { post.Title = value }
+func setContent(post *model.Post, value string)  { post.Content = value }
+func setCategory(post *model.Post, value string) { post.Category = value }
+
+func TestTextFieldsAreTrimmedBeforeValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		want    string
+		wantTag string
+	}{
+		{"only spaces", strings.Repeat(" ", 25), "", "required"},
+		{"only mixed whitespace", " \t\n\r " + strings.Repeat(" ", 20), "", "required"},
+		{"short title padded past the minimum", "  Nineteen characters  ", "Nineteen characters", "minrunes"},
+		{"padded title at the minimum", "   Exactly twenty chars   ", "Exactly twenty chars", ""},
+		{"padded title over the minimum", "\t A title long enough to pass \n", "A title long enough to pass", ""},
+		{"inner spacing and case are kept", "  A Title  With  Inner  Spacing ", "A Title  With  Inner  Spacing", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			post := validPost()
+			post.Title = test.title
+
+			failed, bound := bindPost(t, post, "title")
+			if bound.Title != test.want {
+				t.Errorf("title = %q, want %q", bound.Title, test.want)
+			}
+			if test.wantTag == "" && len(failed) > 0 {
+				t.Errorf("title failed %v, want valid", failed)
+			}
+			if test.wantTag != "" && (len(failed) != 1 || failed[0] != test.wantTag) {
+				t.Errorf("title failed %v, want [%s]", failed, test.wantTag)
+			}
+		})
+	}
+}
+
+func TestWhitespaceOnlyFieldsAreRequired(t *testing.T) {
+	tests := []struct {
+		field string
+		set   func(*model.Post, string)
+	}{
+		{"title", setTitle},
+		{"content", setContent},
+		{"category", setCategory},
+	}
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			post := validPost()
+			test.set(&post, strings.Repeat(" ", 250))
+
+			failed, _ := bindPost(t, post, test.field)
+			if len(failed) != 1 || (failed[0] != "required" && failed[0] != "required_without") {
+				t.Errorf("%s failed %v, want it required", test.field, failed)
+			}
+		})
+	}
+}
+
+func TestCategoryNameFitsItsColumn(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{strings.Repeat("c", 100), true},
+		{strings.Repeat("c", 101), false},
+	}
+	for _, test := range tests {
+		body, err := json.Marshal(model.Category{Name: test.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var category model.Category
+		if err := normalizedBinding.BindBody(body, &category); (err == nil) != test.valid {
+			t.Errorf("name of %d characters: err = %v, want valid %t", len(test.name), err, test.valid)
+		}
+	}
+}
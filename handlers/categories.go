@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+)
+
+type CategoryHandler struct {
+	categories repository.CategoryRepository
+	posts      *PostHandler
+}
+
+func NewCategoryHandler(categories repository.CategoryRepository, posts *PostHandler) *CategoryHandler {
+	return &CategoryHandler{categories: categories, posts: posts}
+}
+
+// parseCategory binds and validates a category request body, answering 400
+// itself when it is invalid.
+func parseCategory(context *gin.Context) (model.Category, bool) {
+	var category model.Category
+	if err := context.ShouldBindWith(&category, normalizedBinding); err != nil {
+		respondBindError(context, err)
+		return model.Category{}, false
+	}
+	return category, true
+}
+
+// GetCategories lists every category by name.
+//
+//	@Summary	List categories
+//	@Tags		categories
+//	@Produce	json
+//	@Success	200	{object}	Response{data=[]model.Category}
+//	@Router		/category [get]
+func (h *CategoryHandler) GetCategories(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	categories, err := h.categories.GetAll(ctx)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, categories, nil)
+}
+
+// GetCategoryByID returns a single category.
+//
+//	@Summary	Get a category
+//	@Tags		categories
+//	@Produce	json
+//	@Param		id	path		int	true	"Category ID"
+//	@Success	200	{object}	Response{data=model.Category}
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/category/{id} [get]
+func (h *CategoryHandler) GetCategoryByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	categoryID, ok := parseID(context, "category")
+	if !ok {
+		return
+	}
+
+	category, err := h.categories.GetByID(ctx, categoryID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, category, nil)
+}
+
+// GetCategoryPosts lists the posts in a category, accepting the same query
+// parameters as GET /article.
+//
+//	@Summary	List the articles in a category
+//	@Tags		categories
+//	@Produce	json
+//	@Param		id		path		int		true	"Category ID"
+//	@Param		page	query		int		false	"Page number"	default(1)
+//	@Param		limit	query		int		false	"Page size"		default(10)
+//	@Param		fields	query		string	false	"Comma-separated post fields to return"
+//	@Success	200		{object}	Response{data=[]model.Post,meta=model.Pagination}
+//	@Header		200		{string}	Link	"first, prev, next and last page links"
+//	@Failure	400		{object}	Response
+//	@Failure	404		{object}	Response
+//	@Router		/category/{id}/articles [get]
+func (h *CategoryHandler) GetCategoryPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	categoryID, ok := parseID(context, "category")
+	if !ok {
+		return
+	}
+
+	if _, err := h.categories.GetByID(ctx, categoryID); err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	h.posts.listPosts(context, model.PostFilter{CategoryID: categoryID})
+}
+
+// AddCategory creates a category.
+//
+//	@Summary	Create a category
+//	@Tags		categories
+//	@Accept		json
+//	@Produce	json
+//	@Param		category	body		model.Category	true	"Name of at least 3 characters"
+//	@Success	201			{object}	Response{data=model.Category}
+//	@Failure	400			{object}	Response
+//	@Failure	401			{object}	Response
+//	@Failure	403			{object}	Response
+//	@Failure	409			{object}	Response
+//	@Failure	413			{object}	Response
+//	@Failure	422			{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/category [post]
+func (h *CategoryHandler) AddCategory(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	category, ok := parseCategory(context)
+	if !ok {
+		return
+	}
+
+	category, err := h.categories.Create(ctx, category)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusCreated, category, nil)
+}
+
+// UpdateCategoryByID renames a category.
+//
+//	@Summary	Rename a category
+//	@Tags		categories
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int				true	"Category ID"
+//	@Param		category	body		model.Category	true	"Name of at least 3 characters"
+//	@Success	200			{object}	Response{data=model.Category}
+//	@Failure	400			{object}	Response
+//	@Failure	401			{object}	Response
+//	@Failure	403			{object}	Response
+//	@Failure	404			{object}	Response
+//	@Failure	409			{object}	Response
+//	@Failure	413			{object}	Response
+//	@Failure	422			{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/category/{id} [put]
+func (h *CategoryHandler) UpdateCategoryByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	categoryID, ok := parseID(context, "category")
+	if !ok {
+		return
+	}
+
+	category, ok := parseCategory(context)
+	if !ok {
+		return
+	}
+
+	category, err := h.categories.Update(ctx, categoryID, category)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, category, nil)
+}
+
+// DeleteCategoryByID removes a category that no post references any more.
+//
+//	@Summary	Delete a category
+//	@Tags		categories
+//	@Produce	json
+//	@Param		id	path		int	true	"Category ID"
+//	@Success	200	{object}	Response
+//	@Failure	400	{object}	Response
+//	@Failure	401	{object}	Response
+//	@Failure	403	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Failure	409	{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/category/{id} [delete]
+func (h *CategoryHandler) DeleteCategoryByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	categoryID, ok := parseID(context, "category")
+	if !ok {
+		return
+	}
+
+	if err := h.categories.Delete(ctx, categoryID); err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, gin.H{"message": "category deleted"}, nil)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+	"backend-projects/article-api/storage"
+)
+
+// coverImageTypes maps the image types accepted as covers to the extension
+// they are stored under.
+var coverImageTypes = map[string]string{
+	"image/gif":  ".gif",
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type CoverHandler struct {
+	posts    repository.PostRepository
+	store    storage.CoverStore
+	maxBytes int64
+}
+
+func NewCoverHandler(posts repository.PostRepository, store storage.CoverStore, maxBytes int64) *CoverHandler {
+	return &CoverHandler{posts: posts, store: store, maxBytes: maxBytes}
+}
+
+// UploadCover stores the image sent in the cover form field and makes it the
+// post's cover image. The image type is detected from the file itself rather
+// than taken from the client. A cover uploaded earlier for the post is
+// removed once the new one is in place.
+//
+//	@Summary	Upload a cover image for an article
+//	@Tags		articles
+//	@Accept		mpfd
+//	@Produce	json
+//	@Param		id		path		int		true	"Post ID"
+//	@Param		cover	formData	file	true	"JPEG, PNG, GIF or WebP image"
+//	@Success	200		{object}	Response{data=model.PostCover}
+//	@Failure	400		{object}	Response
+//	@Failure	401		{object}	Response
+//	@Failure	403		{object}	Response
+//	@Failure	404		{object}	Response
+//	@Failure	413		{object}	Response
+//	@Failure	415		{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id}/cover [post]
+func (h *CoverHandler) UploadCover(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !canEdit(context, post) {
+		return
+	}
+
+	header, err := context.FormFile("cover")
+	if errors.Is(err, http.ErrMissingFile) {
+		respondError(context, http.StatusBadRequest, "cover file is required")
+		return
+	}
+	if err != nil {
+		respondBindError(context, err)
+		return
+	}
+	if header.Size > h.maxBytes {
+		respondError(context, http.StatusRequestEntityTooLarge, fmt.Sprintf("cover image must be at most %d bytes", h.maxBytes))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	extension, ok := coverImageTypes[contentType]
+	if !ok {
+		respondError(context, http.StatusUnsupportedMediaType, "cover must be a JPEG, PNG, GIF or WebP image")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Every upload gets a fresh key, so caches never serve a replaced image
+	// under the new cover's URL.
+	key := coverKeyPrefix(postID) + uuid.NewString() + extension
+	coverURL, err := h.store.Put(context.Request.Context(), key, file, contentType)
+	if err != nil {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, err := h.posts.Patch(ctx, postID, model.PostPatch{CoverImageURL: &coverURL}); err != nil {
+		if err := h.store.Delete(context.Request.Context(), coverURL); err != nil {
+			slog.Error("removing orphaned cover image", "url", coverURL, "error", err)
+		}
+		respondDBError(context, err)
+		return
+	}
+	// PATCH accepts any image URL, including another post's upload, so only
+	// a cover uploaded for this post is removed.
+	if strings.HasPrefix(path.Base(post.CoverImageURL), coverKeyPrefix(postID)) {
+		if err := h.store.Delete(context.Request.Context(), post.CoverImageURL); err != nil {
+			slog.Error("removing replaced cover image", "url", post.CoverImageURL, "error", err)
+		}
+	}
+
+	respond(context, http.StatusOK, model.PostCover{ID: postID, CoverImageURL: coverURL}, nil)
+}
+
+// coverKeyPrefix starts the key of every cover uploaded for the post.
+func coverKeyPrefix(postID int) string {
+	return fmt.Sprintf("%d-", postID)
+}
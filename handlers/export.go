@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-projects/article-api/model"
+)
+
+// exportFlushEvery is how many posts ExportPosts writes between flushes.
+const exportFlushEvery = 100
+
+// ExportPosts streams every post, including drafts and trashed ones, for
+// backups. Posts are read from the database in batches and written as they
+// arrive, so the export never holds the whole table in memory. The body is
+// newline-delimited JSON, one post per line, or a JSON array with
+// format=json. An error after the first post has been sent cuts the body
+// short, leaving the last line or the array incomplete.
+//
+//	@Summary	Export all articles
+//	@Tags		articles
+//	@Produce	json
+//	@Produce	application/x-ndjson
+//	@Param		status	query		string	false	"Only export posts with this status"	Enums(publish, draft, trash)
+//	@Param		format	query		string	false	"Body format"							Enums(ndjson, json)	default(ndjson)
+//	@Success	200		{array}		model.Post
+//	@Failure	400		{object}	Response
+//	@Failure	401		{object}	Response
+//	@Failure	403		{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/export [get]
+func (h *PostHandler) ExportPosts(context *gin.Context) {
+	status := context.Query("status")
+	if status != "" {
+		if err := model.ValidateStatus(status); err != nil {
+			respondError(context, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	format := context.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "json" {
+		respondError(context, http.StatusBadRequest, "format must be ndjson or json")
+		return
+	}
+
+	// The export is bounded by its route timeout rather than QueryTimeout,
+	// which a large table would outlast.
+	ctx := context.Request.Context()
+
+	contentType, extension := "application/x-ndjson", "ndjson"
+	if format == "json" {
+		contentType, extension = "application/json; charset=utf-8", "json"
+	}
+	filename := fmt.Sprintf("articles-%s.%s", time.Now().UTC().Format(time.DateOnly), extension)
+
+	written := 0
+	encoder := json.NewEncoder(context.Writer)
+	err := h.posts.Export(ctx, status, func(post model.Post) error {
+		if written == 0 {
+			context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			context.Header("Content-Type", contentType)
+			context.Status(http.StatusOK)
+			if format == "json" {
+				context.Writer.WriteString("[\n")
+			}
+		} else if format == "json" {
+			context.Writer.WriteString(",\n")
+		}
+		if err := encoder.Encode(post); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			context.Writer.Flush()
+		}
+		return nil
+	})
+	switch {
+	case err != nil && written == 0:
+		respondDBError(context, err)
+	case err != nil:
+		context.Error(err)
+	case written == 0:
+		context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		context.Header("Content-Type", contentType)
+		context.Status(http.StatusOK)
+		if format == "json" {
+			context.Writer.WriteString("[]\n")
+		}
+	case format == "json":
+		context.Writer.WriteString("]\n")
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-projects/article-api/model"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Category    string `xml:"category"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// GetFeed serves the most recently created published articles as RSS 2.0.
+//
+//	@Summary	RSS feed of published articles
+//	@Tags		feed
+//	@Produce	xml
+//	@Success	200	{string}	string	"RSS 2.0 document"
+//	@Router		/feed.xml [get]
+func (h *PostHandler) GetFeed(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	posts, _, err := h.posts.GetAll(ctx, model.PostFilter{
+		Statuses: []string{"publish"},
+		Sort:     model.SortOrder{Field: "created_at", Desc: true},
+		Limit:    FeedSize,
+	})
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "Articles",
+			Link:        PublicBaseURL + "/article",
+			Description: "Recently published articles",
+		},
+	}
+	for _, post := range posts {
+		link := PublicBaseURL + "/article/slug/" + url.PathEscape(post.Slug)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        link,
+			Description: model.ContentHTML(post.Format, post.Content),
+			Category:    post.Category,
+			PubDate:     post.CreatedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	context.Data(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,64 @@
+// Package handlers serves the HTTP API: the article and category endpoints
+// and the middleware in front of them.
+package handlers
+
+import "time"
+
+const (
+	defaultRelatedLimit = 5
+	maxRelatedLimit     = 20
+
+	// maxSuggestions caps the titles GET /article/suggest returns, and
+	// minSuggestQuery is the shortest query, in characters, it answers.
+	maxSuggestions  = 10
+	minSuggestQuery = 2
+
+	// maxBulkIDs caps how many posts one bulk request may touch.
+	maxBulkIDs = 100
+	// maxFetchIDs caps how many posts ?ids= may ask for.
+	maxFetchIDs = 100
+	// maxBatchPosts caps how many posts one batch create may hold.
+	maxBatchPosts = 100
+
+	defaultSort = "-id"
+
+	RequestIDHeader = "X-Request-ID"
+
+	IdempotencyKeyHeader = "Idempotency-Key"
+	APIKeyHeader         = "X-API-Key"
+	// idempotencyReplayedHeader marks a response replayed for a repeated
+	// Idempotency-Key.
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	// requestIDKey is the gin context key holding the current request ID.
+	requestIDKey = "request_id"
+
+	// The values DuplicateContent may take.
+	DuplicateAllow  = "allow"
+	DuplicateWarn   = "warn"
+	DuplicateReject = "reject"
+)
+
+var (
+	// QueryTimeout bounds every database call made while serving a request.
+	QueryTimeout = 5 * time.Second
+
+	// DefaultPageSize is the page size of a list request without a limit;
+	// larger limits than MaxPageSize are lowered to it.
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+
+	// PublicBaseURL is where clients reach the API, used to build absolute
+	// links such as those in the RSS feed.
+	PublicBaseURL = "http://localhost:8080"
+	// ListCacheMaxAge and PostCacheMaxAge are how long lists and single
+	// articles may be cached, when they hold only published articles.
+	ListCacheMaxAge = 30 * time.Second
+	PostCacheMaxAge = 5 * time.Minute
+	// FeedSize is how many articles the RSS feed lists.
+	FeedSize = 20
+	// DuplicateContent is what creating a post whose content matches another
+	// post does: allow it, warn by answering 409 unless the request sets
+	// allow_duplicate=true, or reject it with 409 outright.
+	DuplicateContent = DuplicateAllow
+)
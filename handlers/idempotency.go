@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyStore remembers the response to each Idempotency-Key for ttl, so
+// that a retried request is answered from memory instead of running again.
+// Keys are scoped by the caller's subject, the method and the path.
+type IdempotencyStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+// idempotencyEntry is one key's request and, once done is closed, its
+// response.
+type idempotencyEntry struct {
+	requestHash [sha256.Size]byte
+	done        chan struct{}
+	expires     time.Time
+
+	status      int
+	contentType string
+	// links holds the Location and Link headers, which point at what the
+	// first request created.
+	links http.Header
+	body  []byte
+}
+
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{ttl: ttl, entries: map[string]*idempotencyEntry{}}
+}
+
+// EvictExpired forgets responses older than the ttl. It returns when ctx is
+// done.
+func (s *IdempotencyStore) EvictExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			s.mu.Lock()
+			for key, entry := range s.entries {
+				if !entry.expires.IsZero() && now.After(entry.expires) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Middleware replays the stored response when a request repeats an
+// Idempotency-Key with the same body, and answers 409 when the body differs.
+// A retry arriving while the first request is still running waits for it.
+// Server errors are not remembered, so a request that failed that way can be
+// retried under the same key.
+func (s *IdempotencyStore) Middleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		key := context.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			context.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondError(context, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			context.Abort()
+			return
+		}
+
+		// Keys are only unique per client, so the same key from another
+		// caller or for another endpoint is a different request.
+		subject := ""
+		if claims := requestClaims(context); claims != nil {
+			subject = claims.Subject
+		}
+		key = strings.Join([]string{subject, context.Request.Method, context.Request.URL.Path, key}, "\x00")
+
+		body, err := io.ReadAll(context.Request.Body)
+		if err != nil {
+			respondBindError(context, err)
+			context.Abort()
+			return
+		}
+		context.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := sha256.Sum256(body)
+
+		for {
+			s.mu.Lock()
+			entry, ok := s.entries[key]
+			if ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+				delete(s.entries, key)
+				ok = false
+			}
+			if !ok {
+				entry = &idempotencyEntry{requestHash: requestHash, done: make(chan struct{})}
+				s.entries[key] = entry
+				s.mu.Unlock()
+				s.record(context, key, entry)
+				return
+			}
+			s.mu.Unlock()
+
+			if entry.requestHash != requestHash {
+				respondErrorType(context, http.StatusConflict, ErrorIdempotencyKeyReused, IdempotencyKeyHeader+" was already used with a different request body")
+				context.Abort()
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-context.Request.Context().Done():
+				context.Abort()
+				return
+			}
+			if entry.status == 0 {
+				// The first request failed and was forgotten; run this one
+				// in its place.
+				continue
+			}
+
+			context.Header(idempotencyReplayedHeader, "true")
+			for name, values := range entry.links {
+				context.Writer.Header()[name] = values
+			}
+			context.Data(entry.status, entry.contentType, entry.body)
+			context.Abort()
+			return
+		}
+	}
+}
+
+// record runs the handlers for a new key and keeps their response in entry.
+// A handler that panics leaves the key forgotten, like a server error, before
+// the panic carries on to Recovery.
+func (s *IdempotencyStore) record(context *gin.Context, key string, entry *idempotencyEntry) {
+	writer := &recordingWriter{ResponseWriter: context.Writer}
+	context.Writer = writer
+	defer func() {
+		recovered := recover()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		defer close(entry.done)
+		if recovered != nil {
+			delete(s.entries, key)
+			panic(recovered)
+		}
+		s.keep(key, entry, writer)
+	}()
+	context.Next()
+}
+
+// keep stores the response writer recorded in entry, or forgets key when it
+// was a server error. s.mu must be held.
+func (s *IdempotencyStore) keep(key string, entry *idempotencyEntry, writer *recordingWriter) {
+	if status := writer.Status(); status < http.StatusInternalServerError {
+		entry.status = status
+		entry.contentType = writer.Header().Get("Content-Type")
+		entry.links = http.Header{}
+		for _, name := range []string{"Location", "Link"} {
+			if values := writer.Header().Values(name); values != nil {
+				entry.links[name] = values
+			}
+		}
+		entry.body = writer.body.Bytes()
+		entry.expires = time.Now().Add(s.ttl)
+	} else {
+		delete(s.entries, key)
+	}
+}
+
+// recordingWriter keeps a copy of the body it writes.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sendWithKey posts body to path under the Idempotency-Key key, giving up
+// after a second.
+func sendWithKey(router http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	request := httptest.NewRequestWithContext(ctx, http.MethodPost, path, strings.NewReader(body))
+	request.Header.Set(IdempotencyKeyHeader, key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.POST("/things", NewIdempotencyStore(time.Hour).Middleware(), func(context *gin.Context) {
+		calls++
+		respond(context, http.StatusCreated, gin.H{"call": calls}, nil)
+	})
+
+	first := sendWithKey(router, "/things", "key", `{"a": 1}`)
+	second := sendWithKey(router, "/things", "key", `{"a": 1}`)
+	if calls != 1 || second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("handler ran %d times; replay answered %d %s, want %s", calls, second.Code, second.Body, first.Body)
+	}
+	if second.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Error("replay not marked as one")
+	}
+
+	if reused := sendWithKey(router, "/things", "key", `{"a": 2}`); reused.Code != http.StatusConflict {
+		t.Errorf("different body answered %d, want 409", reused.Code)
+	}
+}
+
+func TestIdempotencyForgetsKeyAfterPanic(t *testing.T) {
+	calls := 0
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	router.POST("/things", NewIdempotencyStore(time.Hour).Middleware(), func(context *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		respond(context, http.StatusCreated, gin.H{"call": calls}, nil)
+	})
+
+	if first := sendWithKey(router, "/things", "key", `{}`); first.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking request answered %d, want 500", first.Code)
+	}
+	retry := sendWithKey(router, "/things", "key", `{}`)
+	if retry.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry answered %d after %d calls, want 201 after 2", retry.Code, calls)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+)
+
+// importColumns are the columns an import file must have. An author column
+// may also be given.
+var importColumns = []string{"title", "content", "category", "status"}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	// IDs are the created posts, in file order.
+	IDs     []int        `json:"ids"`
+	Skipped []SkippedRow `json:"skipped"`
+}
+
+// SkippedRow is a row that was not imported. Row counts the header as row 1,
+// as a spreadsheet does.
+type SkippedRow struct {
+	Row    int          `json:"row"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportPosts creates posts from the rows of an uploaded CSV file. The first
+// row names the columns, in any order. Each row is checked as AddPost checks
+// a body; invalid rows are skipped and reported, and the others are stored in
+// one transaction, so either every valid row is imported or, when one cannot
+// be stored, none are. Rows without an author are credited to the caller.
+//
+//	@Summary	Import articles from CSV
+//	@Tags		articles
+//	@Accept		multipart/form-data
+//	@Produce	json
+//	@Param		file			formData	file	true	"CSV with columns title, content, category, status and optionally author"
+//	@Param		allow_duplicate	query		bool	false	"Import rows even if posts with the same content exist, unless duplicates are rejected outright"
+//	@Success	200				{object}	Response{data=ImportResult}
+//	@Failure	400				{object}	Response
+//	@Failure	401				{object}	Response
+//	@Failure	403				{object}	Response
+//	@Failure	409				{object}	Response
+//	@Failure	413				{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/import [post]
+func (h *PostHandler) ImportPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	header, err := context.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		respondError(context, http.StatusBadRequest, "file is required")
+		return
+	}
+	if err != nil {
+		respondBindError(context, err)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	names, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		respondError(context, http.StatusBadRequest, "file is empty")
+		return
+	}
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	columns, err := importHeader(names)
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subject := requestClaims(context).Subject
+	result := ImportResult{IDs: []int{}, Skipped: []SkippedRow{}}
+	var posts []model.Post
+	var rows []int
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, csv.ErrFieldCount) {
+			result.Skipped = append(result.Skipped, SkippedRow{Row: row, Errors: []FieldError{{Field: "row", Message: fmt.Sprintf("Row must have %d fields", len(names))}}})
+			continue
+		}
+		if err != nil {
+			// Anything else, such as a stray quote, leaves the rest of the
+			// file unreadable.
+			respondError(context, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		post := model.Post{
+			Title:    record[columns["title"]],
+			Content:  record[columns["content"]],
+			Category: record[columns["category"]],
+			Status:   strings.TrimSpace(record[columns["status"]]),
+			AuthorID: &subject,
+		}
+		if i, ok := columns["author"]; ok {
+			post.Author = record[i]
+		}
+		post.Normalize()
+		if post.Author == "" {
+			post.Author = subject
+		}
+		if err := binding.Validator.ValidateStruct(&post); err != nil {
+			var validationErrs validator.ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				respondError(context, http.StatusInternalServerError, err.Error())
+				return
+			}
+			result.Skipped = append(result.Skipped, SkippedRow{Row: row, Errors: fieldErrors(validationErrs)})
+			continue
+		}
+		duplicateOf, err := h.duplicateOf(context, ctx, post.Content)
+		if err != nil {
+			respondDBError(context, err)
+			return
+		}
+		if duplicateOf != 0 {
+			result.Skipped = append(result.Skipped, SkippedRow{Row: row, Errors: []FieldError{{Field: "content", Message: "Content is the same as " + duplicateMessage(duplicateOf)}}})
+			continue
+		}
+		posts = append(posts, post)
+		rows = append(rows, row)
+	}
+
+	if len(posts) > 0 {
+		created, err := h.posts.CreateBatch(ctx, posts)
+		var batchErr *repository.BatchError
+		if errors.As(err, &batchErr) {
+			status, errorType, message := dbErrorStatus(batchErr.Err)
+			respondErrorType(context, status, errorType, fmt.Sprintf("row %d: %s", rows[batchErr.Index], message))
+			return
+		}
+		if err != nil {
+			respondDBError(context, err)
+			return
+		}
+		for _, post := range created {
+			result.IDs = append(result.IDs, post.ID)
+			h.notifyPublished(nil, post)
+		}
+		result.Imported = len(created)
+	}
+
+	respond(context, http.StatusOK, result, nil)
+}
+
+// importHeader maps each column of an import file's header row to its
+// position, ignoring case and a leading byte order mark.
+func importHeader(names []string) (map[string]int, error) {
+	columns := make(map[string]int, len(names))
+	for i, name := range names {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("column %s appears twice", name)
+		}
+		columns[name] = i
+	}
+	var missing []string
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if missing != nil {
+		return nil, fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LimitBody caps how much of a request body handlers may read, so an
+// oversized payload fails instead of being buffered whole. Routes listed in
+// overrides, by their registered path, get their own cap instead.
+func LimitBody(maxBytes int64, overrides map[string]int64) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		limit := maxBytes
+		if override, ok := overrides[context.FullPath()]; ok {
+			limit = override
+		}
+		context.Request.Body = http.MaxBytesReader(context.Writer, context.Request.Body, limit)
+		context.Next()
+	}
+}
+
+// timeoutGrace is how much longer than its timeout a route with its own
+// timeout may spend writing, so that a late 503 can still be sent.
+const timeoutGrace = 5 * time.Second
+
+// Timeout gives each request a deadline, which database calls made through
+// queryContext inherit, and answers 503 if the handler then returns without
+// responding. Routes listed in overrides, by their registered path, get their
+// own timeout, and their write deadline is moved to match since it may run
+// past the server's write timeout. It must come before GzipResponses so the
+// 503 is not written into a finished gzip stream.
+func Timeout(timeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
+	return func(ginContext *gin.Context) {
+		limit, override := overrides[ginContext.FullPath()]
+		if !override {
+			limit = timeout
+		}
+		ctx, cancel := context.WithTimeout(ginContext.Request.Context(), limit)
+		defer cancel()
+		ginContext.Request = ginContext.Request.WithContext(ctx)
+		if override {
+			deadline, _ := ctx.Deadline()
+			if err := http.NewResponseController(ginContext.Writer).SetWriteDeadline(deadline.Add(timeoutGrace)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				ginContext.Error(err)
+			}
+		}
+
+		writer := ginContext.Writer
+		ginContext.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !writer.Written() {
+			ginContext.Writer = writer
+			respondErrorType(ginContext, http.StatusServiceUnavailable, ErrorTimeout, fmt.Sprintf("request did not finish within %s", limit))
+		}
+	}
+}
+
+// RequestLogger assigns every request an ID, echoed back in the X-Request-ID
+// header, and logs one structured line per request once it completes. An ID
+// supplied by the client is kept so it can be traced across services.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		start := time.Now()
+
+		requestID := context.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		context.Set(requestIDKey, requestID)
+		context.Header(RequestIDHeader, requestID)
+
+		context.Next()
+
+		logger.Info("request",
+			slog.String("request_id", requestID),
+			slog.String("method", context.Request.Method),
+			slog.String("path", context.Request.URL.Path),
+			slog.Int("status", context.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", context.ClientIP()),
+		)
+	}
+}
+
+// GzipResponses compresses response bodies of at least minSize bytes for
+// clients that accept gzip. Smaller bodies are sent as is, since compressing
+// them costs more than it saves.
+func GzipResponses(level, minSize int) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if context.Request.Method == http.MethodHead || !strings.Contains(context.GetHeader("Accept-Encoding"), "gzip") {
+			context.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: context.Writer, level: level, minSize: minSize}
+		context.Writer = writer
+		defer writer.finish()
+
+		context.Next()
+	}
+}
+
+// gzipResponseWriter buffers the start of a response until it is known to be
+// worth compressing, then streams the rest through a gzip.Writer.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	level   int
+	minSize int
+
+	buffer      []byte
+	gzip        *gzip.Writer
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	switch {
+	case w.gzip != nil:
+		return w.gzip.Write(data)
+	case w.passthrough:
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buffer = append(w.buffer, data...)
+	if len(w.buffer) >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *gzipResponseWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+// Flush starts compressing right away, so streamed responses are not held
+// back waiting for minSize bytes.
+func (w *gzipResponseWriter) Flush() {
+	if w.gzip == nil && !w.passthrough {
+		w.start()
+	}
+	if w.gzip != nil {
+		w.gzip.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start commits to compressing the response, unless the handler already
+// encoded it, and writes out whatever was buffered.
+func (w *gzipResponseWriter) start() error {
+	buffer := w.buffer
+	w.buffer = nil
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		w.passthrough = true
+		_, err := w.ResponseWriter.Write(buffer)
+		return err
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+
+	gzipWriter, err := gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	if err != nil {
+		return err
+	}
+	w.gzip = gzipWriter
+	_, err = w.gzip.Write(buffer)
+	return err
+}
+
+// finish writes out a response that stayed below minSize, or closes the gzip
+// stream.
+func (w *gzipResponseWriter) finish() {
+	if w.gzip != nil {
+		w.gzip.Close()
+		return
+	}
+	if len(w.buffer) > 0 {
+		w.ResponseWriter.Write(w.buffer)
+	}
+}
+
+// HTTPMetrics holds the Prometheus collectors describing served requests.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	panics   *prometheus.CounterVec
+}
+
+func NewHTTPMetrics(registry prometheus.Registerer) *HTTPMetrics {
+	metrics := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_panics_total",
+			Help: "Handler panics recovered, by method and route.",
+		}, []string{"method", "route"}),
+	}
+	registry.MustRegister(metrics.requests, metrics.duration, metrics.inFlight, metrics.panics)
+	return metrics
+}
+
+// Middleware records every request under its route pattern rather than its
+// path, so /article/1 and /article/2 share one series.
+func (m *HTTPMetrics) Middleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		context.Next()
+
+		route := context.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := context.Request.Method
+		m.requests.WithLabelValues(method, route, strconv.Itoa(context.Writer.Status())).Inc()
+		m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Recovery turns a panicking handler into a 500 in the usual error envelope,
+// logging the panic and its stack with the request ID and counting it. The
+// client never sees the panic itself. It must come after RequestLogger, for
+// the request ID, and before GzipResponses, so the 500 is not written into a
+// finished gzip stream. A panic with http.ErrAbortHandler is passed on, as
+// it asks the server to drop the connection.
+func (m *HTTPMetrics) Recovery() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		writer := context.Writer
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			route := context.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+			m.panics.WithLabelValues(context.Request.Method, route).Inc()
+			slog.Error("handler panicked",
+				slog.String("request_id", context.GetString(requestIDKey)),
+				slog.String("method", context.Request.Method),
+				slog.String("path", context.Request.URL.Path),
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			context.Writer = writer
+			if !writer.Written() {
+				respondError(context, http.StatusInternalServerError, "internal server error")
+			}
+			context.Abort()
+		}()
+		context.Next()
+	}
+}
@@ -0,0 +1,1779 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+	"backend-projects/article-api/webhook"
+)
+
+type PostHandler struct {
+	posts      repository.PostRepository
+	categories repository.CategoryRepository
+	webhooks   *webhook.Notifier
+}
+
+func NewPostHandler(posts repository.PostRepository, categories repository.CategoryRepository, webhooks *webhook.Notifier) *PostHandler {
+	return &PostHandler{posts: posts, categories: categories, webhooks: webhooks}
+}
+
+// notifyPublished sends the publish webhook for post when a write made it
+// live, from previous or as a new post when previous is nil. A post published
+// for a later time is announced by the scheduler once it is due instead.
+func (h *PostHandler) notifyPublished(previous *model.Post, post model.Post) {
+	now := time.Now()
+	if post.IsLive(now) && (previous == nil || !previous.IsLive(now)) {
+		h.webhooks.Notify(webhook.EventPublished, post)
+	}
+}
+
+// resolveCategory fills in the category name for a post that references a
+// category entity. It answers 400 itself, returning false, when the category
+// does not exist.
+func (h *PostHandler) resolveCategory(context *gin.Context, ctx context.Context, categoryID *int, name *string) bool {
+	if categoryID == nil {
+		return true
+	}
+
+	category, err := h.categories.GetByID(ctx, *categoryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCategoryNotFound) {
+			respondError(context, http.StatusBadRequest, "category does not exist")
+		} else {
+			respondDBError(context, err)
+		}
+		return false
+	}
+	*name = category.Name
+	return true
+}
+
+// GetPosts lists posts one page at a time. Each post carries its excerpt in
+// place of the full content. An Accept header asking for text/csv or
+// application/xml gets the page as flat rows instead of the JSON envelope.
+// Trashed posts are left out unless status=trash, include_trash=true or
+// updated_since is given. With ids, it instead returns just those posts, in
+// the order given; ids that match no post are left out, and the other
+// filters do not apply.
+//
+//	@Summary	List articles
+//	@Tags		articles
+//	@Produce	json
+//	@Produce	text/csv
+//	@Produce	xml
+//	@Param		ids				query		string		false	"Comma-separated ids of at most 100 posts to fetch instead of a page"
+//	@Param		page			query		int			false	"Page number"													default(1)	minimum(1)
+//	@Param		limit			query		int			false	"Page size, capped at MAX_PAGE_SIZE (100 unless configured)"	default(10)	minimum(1)
+//	@Param		after			query		int			false	"Cursor: return posts after this id; requires sorting by id"
+//	@Param		featured_first	query		bool		false	"List featured posts before the others"
+//	@Param		sort			query		string		false	"Sort field, prefixed with - for descending"													Enums(id, -id, title, -title, created_at, -created_at, updated_at, -updated_at, view_count, -view_count, like_count, -like_count)	default(-id)
+//	@Param		status			query		[]string	false	"Only posts with one of these comma-separated statuses; without it, trashed posts are left out"	Enums(publish, draft, trash)																										collectionFormat(csv)
+//	@Param		include_trash	query		bool		false	"Include trashed posts when no status is given"
+//	@Param		author			query		string		false	"Only posts by this author"
+//	@Param		tag				query		string		false	"Only posts carrying this tag"
+//	@Param		category		query		string		false	"Only posts in this category, ignoring case (and accents on MySQL)"
+//	@Param		from			query		string		false	"Only posts created at or after this RFC 3339 time or YYYY-MM-DD date"
+//	@Param		to				query		string		false	"Only posts created at or before this RFC 3339 time or YYYY-MM-DD date"
+//	@Param		search			query		string		false	"Case-insensitive match against title and content"
+//	@Param		featured		query		bool		false	"Only featured posts, or only the others"
+//	@Param		updated_since	query		string		false	"Only posts updated after this RFC 3339 time, trashed ones included, for incremental sync"
+//	@Param		fields			query		string		false	"Comma-separated post fields to return, such as id,title,slug; content is only included when listed"
+//	@Success	200				{object}	Response{data=[]model.Post,meta=model.Pagination}
+//	@Header		200				{string}	Link	"first, prev, next and last page links"
+//	@Failure	400				{object}	Response
+//	@Router		/article [get]
+func (h *PostHandler) GetPosts(context *gin.Context) {
+	if ids := context.Query("ids"); ids != "" {
+		h.getPostsByIDs(context, ids)
+		return
+	}
+	h.listPosts(context, model.PostFilter{})
+}
+
+// getPostsByIDs serves the posts listed in ids, a comma-separated list.
+func (h *PostHandler) getPostsByIDs(context *gin.Context, ids string) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	var postIDs []int
+	seen := map[int]bool{}
+	for _, value := range strings.Split(ids, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || id < 1 {
+			respondError(context, http.StatusBadRequest, fmt.Sprintf("ids must be positive integers, not %q", value))
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			postIDs = append(postIDs, id)
+		}
+	}
+	if len(postIDs) > maxFetchIDs {
+		respondError(context, http.StatusBadRequest, fmt.Sprintf("at most %d ids can be fetched at once", maxFetchIDs))
+		return
+	}
+
+	fields, err := parseFields(context.Query("fields"))
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	posts, err := h.posts.GetByIDs(ctx, postIDs, fields)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	setCacheControl(context, ListCacheMaxAge, false, posts...)
+	respondPosts(context, listedPosts(posts, fields), fields, nil)
+}
+
+// listPosts serves a page of posts, narrowing base by the query parameters.
+func (h *PostHandler) listPosts(context *gin.Context, base model.PostFilter) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	page, limit, err := parsePagination(context)
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseFilter(context)
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Lists show live content: trashed posts only appear when asked for,
+	// or to sync clients, which need them to delete their copies.
+	includeTrash := false
+	if value := context.Query("include_trash"); value != "" {
+		if includeTrash, err = strconv.ParseBool(value); err != nil {
+			respondError(context, http.StatusBadRequest, "include_trash must be true or false")
+			return
+		}
+	}
+	filter.ExcludeTrash = !includeTrash && filter.UpdatedSince.IsZero()
+	filter.CategoryID = base.CategoryID
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	filter.Fields, err = parseFields(context.Query("fields"))
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter.Sort, err = parseSort(context.DefaultQuery("sort", defaultSort))
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if value := context.Query("featured_first"); value != "" {
+		if filter.Sort.FeaturedFirst, err = strconv.ParseBool(value); err != nil {
+			respondError(context, http.StatusBadRequest, "featured_first must be true or false")
+			return
+		}
+	}
+
+	if after := context.Query("after"); after != "" {
+		h.getPostsAfter(context, filter, after)
+		return
+	}
+
+	posts, total, err := h.posts.GetAll(ctx, filter)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	meta := model.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}
+	if filter.Sort.Field == "id" && len(posts) > 0 && filter.Offset+len(posts) < total {
+		meta.NextCursor = &posts[len(posts)-1].ID
+	}
+	setPageLinks(context, page, meta.TotalPages)
+	setCacheControl(context, ListCacheMaxAge, filter.PublishedOnly(), posts...)
+	respondPosts(context, listedPosts(posts, filter.Fields), filter.Fields, meta)
+}
+
+// setPageLinks adds an RFC 8288 Link header pointing at the first, previous,
+// next and last pages. Each link keeps the request's other query parameters.
+func setPageLinks(context *gin.Context, page, totalPages int) {
+	lastPage := max(totalPages, 1)
+
+	var links []string
+	link := func(rel string, page int) {
+		query := context.Request.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		links = append(links, fmt.Sprintf(`<%s%s?%s>; rel="%s"`, PublicBaseURL, context.Request.URL.Path, query.Encode(), rel))
+	}
+	link("first", 1)
+	if page > 1 {
+		link("prev", min(page-1, lastPage))
+	}
+	if page < lastPage {
+		link("next", page+1)
+	}
+	link("last", lastPage)
+
+	context.Header("Link", strings.Join(links, ", "))
+}
+
+// parseFields reads the fields query parameter: a comma-separated list of
+// model.PostFields entries, or empty for every field.
+func parseFields(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(model.PostFields, field) {
+			return nil, fmt.Errorf("unknown field %q; fields must be among %s", field, strings.Join(model.PostFields, ", "))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// listedPosts prepares posts for a list response: the content is dropped
+// unless fields asks for it.
+func listedPosts(posts []model.Post, fields []string) []model.Post {
+	if slices.Contains(fields, "content") {
+		return posts
+	}
+	return omitContent(posts)
+}
+
+// sparsePosts encodes each post as an object holding only fields.
+func sparsePosts(posts []model.Post, fields []string) ([]map[string]json.RawMessage, error) {
+	sparse := make([]map[string]json.RawMessage, len(posts))
+	for i, post := range posts {
+		encoded, err := json.Marshal(post)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &all); err != nil {
+			return nil, err
+		}
+		sparse[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				sparse[i][field] = value
+			}
+		}
+	}
+	return sparse, nil
+}
+
+// omitContent drops the full content from posts so list responses stay small;
+// clients read Excerpt there and fetch a single post for the rest.
+func omitContent(posts []model.Post) []model.Post {
+	for i := range posts {
+		posts[i].Content = ""
+	}
+	return posts
+}
+
+// getPostsAfter serves the cursor-paginated variant of GetPosts, which stays
+// stable while posts are added or removed. Rows are ordered by id and the
+// cursor is the id of the last post already seen.
+func (h *PostHandler) getPostsAfter(context *gin.Context, filter model.PostFilter, after string) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	cursor, err := strconv.Atoi(after)
+	if err != nil || cursor < 1 {
+		respondError(context, http.StatusBadRequest, "after must be a positive integer")
+		return
+	}
+	if filter.Sort.Field != "id" || filter.Sort.FeaturedFirst {
+		respondError(context, http.StatusBadRequest, "after can only be combined with sort=id or sort=-id")
+		return
+	}
+
+	limit := filter.Limit
+	filter.After = cursor
+	filter.Offset = 0
+	// One extra row tells whether another page follows.
+	filter.Limit = limit + 1
+
+	posts, _, err := h.posts.GetAll(ctx, filter)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	meta := model.CursorPagination{Limit: limit}
+	if len(posts) > limit {
+		posts = posts[:limit]
+		meta.NextCursor = &posts[limit-1].ID
+	}
+	setCacheControl(context, ListCacheMaxAge, filter.PublishedOnly(), posts...)
+	respondPosts(context, listedPosts(posts, filter.Fields), filter.Fields, meta)
+}
+
+// postColumnNames name the flat columns a post is exported as, in the order
+// postRecord fills them.
+var postColumnNames = []string{"id", "title", "slug", "format", "excerpt", "cover_image_url", "word_count", "reading_time_minutes", "category", "category_id", "author", "status", "featured", "publish_at", "version", "view_count", "like_count", "tags", "created_at", "updated_at"}
+
+// postRecord flattens post into one value per postColumnNames entry. Missing
+// optional values are empty and tags are joined with commas.
+func postRecord(post model.Post) []string {
+	var categoryID, publishAt string
+	if post.CategoryID != nil {
+		categoryID = strconv.Itoa(*post.CategoryID)
+	}
+	if post.PublishAt != nil {
+		publishAt = post.PublishAt.Format(time.RFC3339)
+	}
+	return []string{
+		strconv.Itoa(post.ID),
+		post.Title,
+		post.Slug,
+		post.Format,
+		post.Excerpt,
+		post.CoverImageURL,
+		strconv.Itoa(post.WordCount),
+		strconv.Itoa(post.ReadingTimeMinutes),
+		post.Category,
+		categoryID,
+		post.Author,
+		post.Status,
+		strconv.FormatBool(post.Featured),
+		publishAt,
+		strconv.Itoa(post.Version),
+		strconv.Itoa(post.ViewCount),
+		strconv.Itoa(post.LikeCount),
+		strings.Join(post.Tags, ","),
+		post.CreatedAt.Format(time.RFC3339),
+		post.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// respondPosts answers a list of posts in the format the Accept header asks
+// for. JSON carries meta in the usual envelope; CSV and XML hold only the
+// posts, one row or element per post, and are offered as a download. When
+// fields is set, only those fields are written.
+func respondPosts(context *gin.Context, posts []model.Post, fields []string, meta any) {
+	context.Writer.Header().Add("Vary", "Accept")
+	switch context.NegotiateFormat(gin.MIMEJSON, "text/csv", gin.MIMEXML) {
+	case "text/csv":
+		context.Header("Content-Disposition", `attachment; filename="articles.csv"`)
+		context.Header("Content-Type", "text/csv; charset=utf-8")
+		context.Status(http.StatusOK)
+
+		columns := recordColumns(fields)
+		writer := csv.NewWriter(context.Writer)
+		writer.Write(pick(postColumnNames, columns))
+		for _, post := range posts {
+			writer.Write(pick(postRecord(post), columns))
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			context.Error(err)
+		}
+	case gin.MIMEXML:
+		context.Header("Content-Disposition", `attachment; filename="articles.xml"`)
+		context.Header("Content-Type", "application/xml; charset=utf-8")
+		context.Status(http.StatusOK)
+
+		if err := writePostsXML(context.Writer, posts, recordColumns(fields)); err != nil {
+			context.Error(err)
+		}
+	default:
+		if fields == nil {
+			respond(context, http.StatusOK, posts, meta)
+			return
+		}
+		sparse, err := sparsePosts(posts, fields)
+		if err != nil {
+			respondError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respond(context, http.StatusOK, sparse, meta)
+	}
+}
+
+// recordColumns returns the indexes into postColumnNames of the columns
+// fields selects, or all of them when fields is nil.
+func recordColumns(fields []string) []int {
+	var columns []int
+	for i, name := range postColumnNames {
+		if fields == nil || slices.Contains(fields, name) {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+// pick returns the values at indexes, in order.
+func pick(values []string, indexes []int) []string {
+	picked := make([]string, len(indexes))
+	for i, index := range indexes {
+		picked[i] = values[index]
+	}
+	return picked
+}
+
+// writePostsXML writes posts as an <articles> document holding one <article>
+// per post, with a child element per postColumnNames entry in columns.
+func writePostsXML(w io.Writer, posts []model.Post, columns []int) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	articles := xml.StartElement{Name: xml.Name{Local: "articles"}}
+	if err := encoder.EncodeToken(articles); err != nil {
+		return err
+	}
+	for _, post := range posts {
+		article := xml.StartElement{Name: xml.Name{Local: "article"}}
+		if err := encoder.EncodeToken(article); err != nil {
+			return err
+		}
+		record := postRecord(post)
+		for _, i := range columns {
+			if err := encoder.EncodeElement(record[i], xml.StartElement{Name: xml.Name{Local: postColumnNames[i]}}); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(article.End()); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(articles.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// parseFilter reads the query parameters shared by the list and count
+// endpoints.
+func parseFilter(context *gin.Context) (model.PostFilter, error) {
+	filter := model.PostFilter{
+		// search matches title and content, ignoring case.
+		Search: strings.TrimSpace(context.Query("search")),
+		Author: strings.TrimSpace(context.Query("author")),
+		Tag:    strings.ToLower(strings.TrimSpace(context.Query("tag"))),
+		// category ignores case, and accents too on MySQL.
+		Category: model.NormalizeCategory(context.Query("category")),
+	}
+
+	// status takes a comma-separated list.
+	if statuses := context.Query("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			status = strings.TrimSpace(status)
+			if err := model.ValidateStatus(status); err != nil {
+				return model.PostFilter{}, err
+			}
+			if !slices.Contains(filter.Statuses, status) {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+
+	var err error
+	if filter.From, err = parseDate(context.Query("from"), false); err != nil {
+		return model.PostFilter{}, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseDate(context.Query("to"), true); err != nil {
+		return model.PostFilter{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return model.PostFilter{}, errors.New("from must not be after to")
+	}
+	if value := context.Query("updated_since"); value != "" {
+		// Sync clients pass back a time they were given, so only a full
+		// timestamp makes sense here.
+		if filter.UpdatedSince, err = time.Parse(time.RFC3339, value); err != nil {
+			return model.PostFilter{}, errors.New("updated_since must be an RFC 3339 time")
+		}
+	}
+	if value := context.Query("featured"); value != "" {
+		featured, err := strconv.ParseBool(value)
+		if err != nil {
+			return model.PostFilter{}, errors.New("featured must be true or false")
+		}
+		filter.Featured = &featured
+	}
+
+	return filter, nil
+}
+
+// parseDate reads an RFC 3339 timestamp or a YYYY-MM-DD date, returning the
+// zero time for an empty value. A bare date means the start of that day, or
+// its last second when endOfDay is set, so that to=2024-05-31 still includes
+// posts created on the 31st.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Second)
+	}
+	return parsed, nil
+}
+
+// CountPosts reports how many posts have each status, honouring the same
+// filters as GetPosts.
+//
+//	@Summary	Count articles by status
+//	@Tags		articles
+//	@Produce	json
+//	@Param		status			query		[]string	false	"Only posts with one of these comma-separated statuses"	Enums(publish, draft, trash)	collectionFormat(csv)
+//	@Param		author			query		string		false	"Only posts by this author"
+//	@Param		tag				query		string		false	"Only posts carrying this tag"
+//	@Param		category		query		string		false	"Only posts in this category, ignoring case (and accents on MySQL)"
+//	@Param		from			query		string		false	"Only posts created at or after this RFC 3339 time or YYYY-MM-DD date"
+//	@Param		to				query		string		false	"Only posts created at or before this RFC 3339 time or YYYY-MM-DD date"
+//	@Param		search			query		string		false	"Case-insensitive match against title and content"
+//	@Param		featured		query		bool		false	"Only featured posts, or only the others"
+//	@Param		updated_since	query		string		false	"Only posts updated after this RFC 3339 time, trashed ones included, for incremental sync"
+//	@Success	200				{object}	Response{data=model.StatusCounts}
+//	@Failure	400				{object}	Response
+//	@Router		/article/count [get]
+func (h *PostHandler) CountPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	filter, err := parseFilter(context)
+	if err != nil {
+		respondError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	counts, err := h.posts.CountByStatus(ctx, filter)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	respond(context, http.StatusOK, model.StatusCounts{
+		Total:   total,
+		Publish: counts["publish"],
+		Draft:   counts["draft"],
+		Trash:   counts["trash"],
+	}, nil)
+}
+
+// parseID reads the :id path parameter, answering 400 "invalid <kind> ID"
+// before any query runs when it is not a positive integer.
+func parseID(context *gin.Context, kind string) (int, bool) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil || id < 1 {
+		respondError(context, http.StatusBadRequest, "invalid "+kind+" ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// parsePagination reads the page and limit query parameters, falling back to
+// page 1 and DefaultPageSize. Limits above MaxPageSize are capped, and the
+// meta of the response shows the limit used.
+func parsePagination(context *gin.Context) (int, int, error) {
+	page := 1
+	if value := context.Query("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, errors.New("page must be a positive integer")
+		}
+		page = parsed
+	}
+
+	limit := DefaultPageSize
+	if value := context.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = min(parsed, MaxPageSize)
+	}
+
+	return page, limit, nil
+}
+
+// parseSort reads a sort query value such as "title" or "-created_at". A
+// leading minus sorts descending.
+func parseSort(value string) (model.SortOrder, error) {
+	order := model.SortOrder{Field: value}
+	if strings.HasPrefix(value, "-") {
+		order = model.SortOrder{Field: value[1:], Desc: true}
+	}
+
+	for _, field := range model.SortFields {
+		if field == order.Field {
+			return order, nil
+		}
+	}
+	return model.SortOrder{}, fmt.Errorf("cannot sort by %q", order.Field)
+}
+
+// GetStats summarizes all posts for a dashboard in one call.
+//
+//	@Summary	Summarize articles
+//	@Tags		articles
+//	@Produce	json
+//	@Success	200	{object}	Response{data=model.Stats}
+//	@Failure	401	{object}	Response
+//	@Failure	403	{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/stats [get]
+func (h *PostHandler) GetStats(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	stats, err := h.posts.Stats(ctx)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, stats, nil)
+}
+
+// GetArchive counts published posts per month of creation, for date-based
+// navigation.
+//
+//	@Summary	Count articles per month
+//	@Tags		articles
+//	@Produce	json
+//	@Success	200	{object}	Response{data=[]model.ArchiveMonth}
+//	@Router		/article/archive [get]
+func (h *PostHandler) GetArchive(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	months, err := h.posts.Archive(ctx)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, months, nil)
+}
+
+// GetRandomPost returns a published post picked at random.
+//
+//	@Summary	Get a random article
+//	@Tags		articles
+//	@Produce	json
+//	@Success	200	{object}	Response{data=model.Post}
+//	@Failure	404	{object}	Response
+//	@Router		/article/random [get]
+func (h *PostHandler) GetRandomPost(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	post, err := h.posts.Random(ctx)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		respondError(context, http.StatusNotFound, "no published articles")
+		return
+	}
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	respond(context, http.StatusOK, post, nil)
+}
+
+// GetPostByID returns a single post, honouring If-None-Match.
+//
+//	@Summary	Get an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id				path		int		true	"Post ID"
+//	@Param		render			query		string	false	"Set to html to get Markdown content rendered as sanitized HTML"	Enums(html)
+//	@Param		If-None-Match	header		string	false	"ETag from an earlier response"
+//	@Success	200				{object}	Response{data=model.Post}
+//	@Success	304				"Not modified since the ETag in If-None-Match"
+//	@Failure	400				{object}	Response
+//	@Failure	404				{object}	Response
+//	@Router		/article/{id} [get]
+func (h *PostHandler) GetPostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !renderPost(context, &post) {
+		return
+	}
+
+	respondPost(context, post)
+}
+
+// GetPostPlainText returns a post's content as plain text, with Markdown
+// rendered and every tag stripped, honouring If-None-Match.
+//
+//	@Summary	Get an article as plain text
+//	@Tags		articles
+//	@Produce	plain
+//	@Param		id				path		int		true	"Post ID"
+//	@Param		If-None-Match	header		string	false	"ETag from an earlier response"
+//	@Success	200				{string}	string	"Content with blank lines between paragraphs"
+//	@Success	304				"Not modified since the ETag in If-None-Match"
+//	@Failure	400				{object}	Response
+//	@Failure	404				{object}	Response
+//	@Router		/article/{id}/plaintext [get]
+func (h *PostHandler) GetPostPlainText(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	text := model.PlainText(post.Format, post.Content)
+	// The ETag covers only the text, which edits to other fields leave as
+	// is.
+	sum := sha256.Sum256([]byte(text))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	context.Header("ETag", etag)
+	setCacheControl(context, PostCacheMaxAge, false, post)
+	if etagMatches(context.GetHeader("If-None-Match"), etag) {
+		context.Status(http.StatusNotModified)
+		return
+	}
+	context.String(http.StatusOK, "%s\n", text)
+}
+
+// GetPostBySlug returns the post with the given slug.
+//
+//	@Summary	Get an article by slug
+//	@Tags		articles
+//	@Produce	json
+//	@Param		slug			path		string	true	"Post slug"
+//	@Param		If-None-Match	header		string	false	"ETag from an earlier response"
+//	@Success	200				{object}	Response{data=model.Post}
+//	@Success	304				"Not modified since the ETag in If-None-Match"
+//	@Failure	404				{object}	Response
+//	@Router		/article/slug/{slug} [get]
+func (h *PostHandler) GetPostBySlug(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	post, err := h.posts.GetBySlug(ctx, context.Param("slug"))
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !renderPost(context, &post) {
+		return
+	}
+
+	respondPost(context, post)
+}
+
+// renderPost applies the render query parameter. render=html turns Markdown
+// content into the sanitized HTML it renders to, after which the post's
+// format is html. It answers 400 itself, returning false, for any other
+// value.
+func renderPost(context *gin.Context, post *model.Post) bool {
+	switch context.Query("render") {
+	case "":
+		return true
+	case model.FormatHTML:
+		post.Content = model.ContentHTML(post.Format, post.Content)
+		post.Format = model.FormatHTML
+		return true
+	}
+	respondError(context, http.StatusBadRequest, "render must be html")
+	return false
+}
+
+// respondPost writes post along with its ETag, or just 304 Not Modified when
+// the client's If-None-Match shows it already has this version.
+func respondPost(context *gin.Context, post model.Post) {
+	etag := postETag(post)
+	context.Header("ETag", etag)
+	setCacheControl(context, PostCacheMaxAge, false, post)
+	if etagMatches(context.GetHeader("If-None-Match"), etag) {
+		context.Status(http.StatusNotModified)
+		return
+	}
+	respond(context, http.StatusOK, post, nil)
+}
+
+// setCacheControl lets browsers and shared caches keep a response for maxAge
+// when it only holds live published posts, which published says is assured
+// by the query, and forbids storing it otherwise so that drafts, trashed and
+// scheduled posts are never cached. A maxAge of 0 asks caches to revalidate
+// every time.
+func setCacheControl(context *gin.Context, maxAge time.Duration, published bool, posts ...model.Post) {
+	if !published {
+		now := time.Now()
+		for _, post := range posts {
+			if !post.IsLive(now) {
+				context.Header("Cache-Control", "no-store")
+				return
+			}
+		}
+	}
+	if maxAge <= 0 {
+		context.Header("Cache-Control", "no-cache")
+		return
+	}
+	context.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+}
+
+// postETag derives a strong ETag from every field of post, so it changes
+// whenever the stored row does.
+func postETag(post model.Post) string {
+	body, _ := json.Marshal(post)
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value names etag.
+// Weak validators match too, as RFC 9110 asks for GET.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AddPost creates a post. Title, content, category, author and status are
+// required; the slug is generated from the title. Content matching another
+// post is refused with 409 as DuplicateContent says.
+//
+//	@Summary	Create an article
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		Idempotency-Key	header		string		false	"Repeating a key with the same body replays the first response instead of creating another article"
+//	@Param		post			body		model.Post	true	"Title of at least 20 characters, content of at least 200, category of at least 3, a non-empty author and a status of publish, draft or trash"
+//	@Param		allow_duplicate	query		bool		false	"Create the article even if another has the same content, unless duplicates are rejected outright"
+//	@Success	201				{object}	Response{data=model.Post}
+//	@Header		201				{string}	Location	"/article/{id} of the new article"
+//	@Header		201				{string}	Link		"The article's slug URL, as rel=alternate"
+//	@Failure	400				{object}	Response
+//	@Failure	401				{object}	Response
+//	@Failure	403				{object}	Response
+//	@Failure	409				{object}	Response
+//	@Failure	413				{object}	Response
+//	@Failure	422				{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article [post]
+func (h *PostHandler) AddPost(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	var newPost model.Post
+	if err := context.ShouldBindWith(&newPost, normalizedBinding); err != nil {
+		respondBindError(context, err)
+		return
+	}
+	if !h.resolveCategory(context, ctx, newPost.CategoryID, &newPost.Category) {
+		return
+	}
+	newPost.AuthorID = &requestClaims(context).Subject
+
+	duplicateOf, err := h.duplicateOf(context, ctx, newPost.Content)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if duplicateOf != 0 {
+		respondErrorType(context, http.StatusConflict, ErrorDuplicateContent, duplicateMessage(duplicateOf))
+		return
+	}
+
+	createdPost, err := h.posts.Create(ctx, newPost)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	h.notifyPublished(nil, createdPost)
+
+	// The id never changes, unlike the slug, so Location uses it; the slug
+	// address is offered alongside.
+	context.Header("Location", fmt.Sprintf("/article/%d", createdPost.ID))
+	context.Header("Link", fmt.Sprintf(`</article/slug/%s>; rel="alternate"`, url.PathEscape(createdPost.Slug)))
+	respond(context, http.StatusCreated, createdPost, nil)
+}
+
+// duplicateOf returns the id of a post whose content matches content, or 0
+// when there is none or DuplicateContent lets this request create it anyway.
+func (h *PostHandler) duplicateOf(context *gin.Context, ctx context.Context, content string) (int, error) {
+	if DuplicateContent == DuplicateAllow || DuplicateContent == DuplicateWarn && context.Query("allow_duplicate") == "true" {
+		return 0, nil
+	}
+	id, err := h.posts.FindDuplicate(ctx, content)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// duplicateMessage explains why content matching post id was refused.
+func duplicateMessage(id int) string {
+	if DuplicateContent == DuplicateWarn {
+		return fmt.Sprintf("article %d already has this content; send allow_duplicate=true to create it anyway", id)
+	}
+	return fmt.Sprintf("article %d already has this content", id)
+}
+
+// BatchItem reports what became of one post of a batch create.
+type BatchItem struct {
+	// Index is the post's position in the request, counting from 0.
+	Index int `json:"index"`
+	// ID is the created post's id, or null when it was not created.
+	ID    *int           `json:"id"`
+	Error *ResponseError `json:"error,omitempty"`
+}
+
+// BatchCreatePosts creates several posts from a JSON array, each checked as
+// by AddPost. By default the batch is all or nothing: any invalid post
+// answers 422, listing fields as "<index>.<field>", and a post that cannot be
+// stored rolls the batch back. With ?atomic=false every valid post is created
+// on its own and the response reports each one.
+//
+//	@Summary	Create several articles
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		posts			body		[]model.Post				true	"Up to 100 posts, each following the create rules"
+//	@Param		atomic			query		bool						false	"Create all posts or none"	default(true)
+//	@Param		allow_duplicate	query		bool						false	"Create posts even if others have the same content, unless duplicates are rejected outright"
+//	@Success	200				{object}	Response{data=[]BatchItem}	"With atomic=false; some items may carry errors"
+//	@Success	201				{object}	Response{data=[]BatchItem}
+//	@Failure	400				{object}	Response
+//	@Failure	401				{object}	Response
+//	@Failure	403				{object}	Response
+//	@Failure	409				{object}	Response
+//	@Failure	413				{object}	Response
+//	@Failure	422				{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/batch [post]
+func (h *PostHandler) BatchCreatePosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	atomic, err := strconv.ParseBool(context.DefaultQuery("atomic", "true"))
+	if err != nil {
+		respondError(context, http.StatusBadRequest, "atomic must be true or false")
+		return
+	}
+
+	body, err := io.ReadAll(context.Request.Body)
+	if err != nil {
+		respondBindError(context, err)
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		respondError(context, http.StatusBadRequest, "request body is empty")
+		return
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		respondError(context, http.StatusBadRequest, "body must be a JSON array of posts")
+		return
+	}
+	if len(raws) == 0 {
+		respondError(context, http.StatusBadRequest, "posts must not be empty")
+		return
+	}
+	if len(raws) > maxBatchPosts {
+		respondError(context, http.StatusBadRequest, fmt.Sprintf("at most %d posts can be created at once", maxBatchPosts))
+		return
+	}
+
+	items := make([]BatchItem, len(raws))
+	posts := make([]model.Post, len(raws))
+	var invalid []FieldError
+	for i, raw := range raws {
+		items[i].Index = i
+		fields, err := h.bindBatchPost(context, ctx, raw, &posts[i])
+		if err != nil {
+			respondDBError(context, err)
+			return
+		}
+		if fields != nil {
+			items[i].Error = &ResponseError{Code: http.StatusUnprocessableEntity, Type: ErrorValidationFailed, Message: "validation failed", Errors: fields}
+			for _, field := range fields {
+				invalid = append(invalid, FieldError{Field: fmt.Sprintf("%d.%s", i, field.Field), Message: field.Message})
+			}
+		}
+	}
+
+	if atomic {
+		if invalid != nil {
+			respondInvalid(context, invalid)
+			return
+		}
+		created, err := h.posts.CreateBatch(ctx, posts)
+		var batchErr *repository.BatchError
+		if errors.As(err, &batchErr) {
+			status, errorType, message := dbErrorStatus(batchErr.Err)
+			respondErrorType(context, status, errorType, fmt.Sprintf("post %d: %s", batchErr.Index, message))
+			return
+		}
+		if err != nil {
+			respondDBError(context, err)
+			return
+		}
+		for i, post := range created {
+			items[i].ID = &post.ID
+			h.notifyPublished(nil, post)
+		}
+		respond(context, http.StatusCreated, items, nil)
+		return
+	}
+
+	for i, post := range posts {
+		if items[i].Error != nil {
+			continue
+		}
+		created, err := h.posts.Create(ctx, post)
+		if err != nil {
+			status, errorType, message := dbErrorStatus(err)
+			items[i].Error = &ResponseError{Code: status, Type: errorType, Message: message}
+			continue
+		}
+		items[i].ID = &created.ID
+		h.notifyPublished(nil, created)
+	}
+	respond(context, http.StatusOK, items, nil)
+}
+
+// bindBatchPost decodes and checks one post of a batch into post, returning
+// the fields that failed. Only an error looking up the category is returned
+// as an error.
+func (h *PostHandler) bindBatchPost(context *gin.Context, ctx context.Context, raw json.RawMessage, post *model.Post) ([]FieldError, error) {
+	if err := normalizedBinding.BindBody(raw, post); err != nil {
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return fieldErrors(validationErrs), nil
+		}
+		message := decodeMessage(err, "Post")
+		if message == "" {
+			message = "Post could not be decoded"
+		}
+		return []FieldError{{Field: "post", Message: message}}, nil
+	}
+	if post.CategoryID != nil {
+		category, err := h.categories.GetByID(ctx, *post.CategoryID)
+		if errors.Is(err, repository.ErrCategoryNotFound) {
+			return []FieldError{{Field: "category_id", Message: "Category does not exist"}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		post.Category = category.Name
+	}
+	post.AuthorID = &requestClaims(context).Subject
+
+	duplicateOf, err := h.duplicateOf(context, ctx, post.Content)
+	if err != nil {
+		return nil, err
+	}
+	if duplicateOf != 0 {
+		return []FieldError{{Field: "content", Message: "Content is the same as " + duplicateMessage(duplicateOf)}}, nil
+	}
+	return nil, nil
+}
+
+// ValidatePost runs the create checks on a post without saving it, and
+// answers with the post as it would be stored. The slug is a preview: a post
+// created meanwhile may take it first.
+//
+//	@Summary	Validate an article without creating it
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		post			body		model.Post	true	"Same rules as create"
+//	@Param		allow_duplicate	query		bool		false	"Accept content another article has, unless duplicates are rejected outright"
+//	@Success	200				{object}	Response{data=model.Post}
+//	@Failure	400				{object}	Response
+//	@Failure	401				{object}	Response
+//	@Failure	403				{object}	Response
+//	@Failure	409				{object}	Response
+//	@Failure	413				{object}	Response
+//	@Failure	422				{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/validate [post]
+func (h *PostHandler) ValidatePost(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	var post model.Post
+	if err := context.ShouldBindWith(&post, normalizedBinding); err != nil {
+		respondBindError(context, err)
+		return
+	}
+	if !h.resolveCategory(context, ctx, post.CategoryID, &post.Category) {
+		return
+	}
+
+	duplicateOf, err := h.duplicateOf(context, ctx, post.Content)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if duplicateOf != 0 {
+		respondErrorType(context, http.StatusConflict, ErrorDuplicateContent, duplicateMessage(duplicateOf))
+		return
+	}
+	if post.Slug, err = h.posts.Slug(ctx, post.Title); err != nil {
+		respondDBError(context, err)
+		return
+	}
+	post.AuthorID = &requestClaims(context).Subject
+	post.WordCount, post.ReadingTimeMinutes = model.ReadingStats(post.Content)
+
+	respond(context, http.StatusOK, post, nil)
+}
+
+// UpdatePostByID replaces a post. The body must carry the version the client
+// last read.
+//
+//	@Summary	Replace an article
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int			true	"Post ID"
+//	@Param		post	body		model.Post	true	"Same rules as create, plus the current version"
+//	@Success	200		{object}	Response{data=model.Post}
+//	@Failure	400		{object}	Response
+//	@Failure	401		{object}	Response
+//	@Failure	403		{object}	Response
+//	@Failure	404		{object}	Response
+//	@Failure	409		{object}	Response
+//	@Failure	413		{object}	Response
+//	@Failure	422		{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id} [put]
+func (h *PostHandler) UpdatePostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	current, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !canEdit(context, current) {
+		return
+	}
+
+	var updatedPost model.Post
+	var fields []FieldError
+	if err := context.ShouldBindWith(&updatedPost, normalizedBinding); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			respondBindError(context, err)
+			return
+		}
+		fields = fieldErrors(validationErrs)
+	}
+	if updatedPost.Version < 1 {
+		fields = append(fields, FieldError{Field: "version", Message: "Version is required"})
+	}
+	if len(fields) > 0 {
+		respondInvalid(context, fields)
+		return
+	}
+	if !h.resolveCategory(context, ctx, updatedPost.CategoryID, &updatedPost.Category) {
+		return
+	}
+
+	updatedPost, err = h.posts.Update(ctx, postID, updatedPost)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	h.notifyPublished(&current, updatedPost)
+
+	respond(context, http.StatusOK, updatedPost, nil)
+}
+
+// PatchPostByID updates only the fields present in the body.
+//
+//	@Summary	Partially update an article
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int				true	"Post ID"
+//	@Param		patch	body		model.PostPatch	true	"Fields to change; each follows the create rules"
+//	@Success	200		{object}	Response{data=model.Post}
+//	@Failure	400		{object}	Response
+//	@Failure	401		{object}	Response
+//	@Failure	403		{object}	Response
+//	@Failure	404		{object}	Response
+//	@Failure	409		{object}	Response
+//	@Failure	413		{object}	Response
+//	@Failure	422		{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id} [patch]
+func (h *PostHandler) PatchPostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	current, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !canEdit(context, current) {
+		return
+	}
+
+	patch := model.PostPatch{CurrentFormat: current.Format, CurrentExcerptGenerated: current.ExcerptGenerated()}
+	if err := context.ShouldBindWith(&patch, normalizedBinding); err != nil {
+		respondBindError(context, err)
+		return
+	}
+
+	provided := false
+	for _, field := range []*string{patch.Title, patch.Content, patch.Format, patch.Excerpt, patch.CoverImageURL, patch.Category, patch.Author, patch.Status} {
+		if field != nil {
+			provided = true
+		}
+	}
+	if patch.Tags != nil {
+		provided = true
+	}
+	if patch.PublishAt != nil {
+		provided = true
+	}
+	if patch.Featured != nil {
+		provided = true
+	}
+	if patch.CategoryID != nil {
+		var name string
+		if !h.resolveCategory(context, ctx, patch.CategoryID, &name) {
+			return
+		}
+		patch.Category = &name
+		provided = true
+	}
+	if !provided {
+		respondError(context, http.StatusBadRequest, "no fields to update")
+		return
+	}
+
+	patchedPost, err := h.posts.Patch(ctx, postID, patch)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	h.notifyPublished(&current, patchedPost)
+
+	respond(context, http.StatusOK, patchedPost, nil)
+}
+
+// DeletePostByID moves a post to the trash. Passing ?permanent=true removes
+// the row instead.
+//
+//	@Summary	Delete an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id			path		int		true	"Post ID"
+//	@Param		permanent	query		bool	false	"Remove the post instead of trashing it"
+//	@Success	200			{object}	Response
+//	@Failure	400			{object}	Response
+//	@Failure	401			{object}	Response
+//	@Failure	403			{object}	Response
+//	@Failure	404			{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id} [delete]
+func (h *PostHandler) DeletePostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	if context.Query("permanent") == "true" {
+		if err := h.posts.Delete(ctx, postID); err != nil {
+			respondDBError(context, err)
+			return
+		}
+		respond(context, http.StatusOK, gin.H{"message": "post deleted"}, nil)
+		return
+	}
+
+	trash := "trash"
+	if _, err := h.posts.Patch(ctx, postID, model.PostPatch{Status: &trash}); err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, gin.H{"message": "post moved to trash"}, nil)
+}
+
+// RestorePostByID moves a trashed post back to draft.
+//
+//	@Summary	Restore a trashed article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.Post}
+//	@Failure	401	{object}	Response
+//	@Failure	403	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Failure	409	{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id}/restore [post]
+func (h *PostHandler) RestorePostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !canEdit(context, post) {
+		return
+	}
+	if post.Status != "trash" {
+		respondError(context, http.StatusConflict, "post is not in trash")
+		return
+	}
+
+	draft := "draft"
+	restoredPost, err := h.posts.Patch(ctx, postID, model.PostPatch{Status: &draft})
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, restoredPost, nil)
+}
+
+// GetRelatedPosts lists published posts that share the post's category or
+// tags, newest first.
+//
+//	@Summary	List related articles
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id		path		int	true	"Post ID"
+//	@Param		limit	query		int	false	"Maximum number of posts (max 20)"	default(5)
+//	@Success	200		{object}	Response{data=[]model.Post}
+//	@Failure	400		{object}	Response
+//	@Failure	404		{object}	Response
+//	@Router		/article/{id}/related [get]
+func (h *PostHandler) GetRelatedPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	limit := defaultRelatedLimit
+	if value := context.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(context, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxRelatedLimit)
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	related, err := h.posts.Related(ctx, post, limit)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, omitContent(related), nil)
+}
+
+// GetNextPost returns the published post created right after the given one.
+//
+//	@Summary	Get the next article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.Post}
+//	@Success	204	"No newer published article"
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/next [get]
+func (h *PostHandler) GetNextPost(context *gin.Context) {
+	h.adjacentPost(context, true)
+}
+
+// GetPreviousPost returns the published post created right before the given
+// one.
+//
+//	@Summary	Get the previous article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.Post}
+//	@Success	204	"No older published article"
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/prev [get]
+func (h *PostHandler) GetPreviousPost(context *gin.Context) {
+	h.adjacentPost(context, false)
+}
+
+// adjacentPost serves the published neighbour of a post, answering 204 when
+// it has none on that side. Only published posts are ever returned, though
+// the post itself may have any status.
+func (h *PostHandler) adjacentPost(context *gin.Context, newer bool) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	adjacent, err := h.posts.Adjacent(ctx, post, newer)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		context.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, adjacent, nil)
+}
+
+// ViewPostByID records one view of a post. Views are not edits, so the
+// version and updated_at stay as they are.
+//
+//	@Summary	Count a view of an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.PostViews}
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/view [post]
+func (h *PostHandler) ViewPostByID(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	viewCount, err := h.posts.AddView(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, model.PostViews{ID: postID, ViewCount: viewCount}, nil)
+}
+
+// SuggestPosts offers the titles of published posts matching what has been
+// typed so far, for a search box. Queries shorter than two characters get an
+// empty list.
+//
+//	@Summary	Suggest article titles
+//	@Tags		articles
+//	@Produce	json
+//	@Param		q	query		string	true	"Text the title should start with or contain, ignoring case"
+//	@Success	200	{object}	Response{data=[]model.Suggestion}
+//	@Router		/article/suggest [get]
+func (h *PostHandler) SuggestPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	query := strings.TrimSpace(context.Query("q"))
+	if utf8.RuneCountInString(query) < minSuggestQuery {
+		respond(context, http.StatusOK, []model.Suggestion{}, nil)
+		return
+	}
+
+	suggestions, err := h.posts.Suggest(ctx, query, maxSuggestions)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	setCacheControl(context, ListCacheMaxAge, true)
+	respond(context, http.StatusOK, suggestions, nil)
+}
+
+// LikePostByID adds one like to a post. Likes are anonymous counts, not
+// edits, so the version and updated_at stay as they are.
+//
+//	@Summary	Like an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.PostLikes}
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/like [post]
+func (h *PostHandler) LikePostByID(context *gin.Context) {
+	h.likePost(context, true)
+}
+
+// UnlikePostByID takes one like away from a post, stopping at zero.
+//
+//	@Summary	Unlike an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=model.PostLikes}
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/like [delete]
+func (h *PostHandler) UnlikePostByID(context *gin.Context) {
+	h.likePost(context, false)
+}
+
+func (h *PostHandler) likePost(context *gin.Context, liked bool) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	likeCount, err := h.posts.Like(ctx, postID, liked)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, model.PostLikes{ID: postID, LikeCount: likeCount}, nil)
+}
+
+// GetPostRevisions lists the earlier states of a post, newest first.
+//
+//	@Summary	List the revisions of an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Success	200	{object}	Response{data=[]model.Revision}
+//	@Failure	400	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Router		/article/{id}/revisions [get]
+func (h *PostHandler) GetPostRevisions(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+
+	revisions, err := h.posts.Revisions(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+
+	respond(context, http.StatusOK, revisions, nil)
+}
+
+// RestorePostRevision rolls a post back to one of its revisions. The state it
+// replaces becomes a revision of its own, so the rollback can be undone.
+//
+//	@Summary	Restore a revision of an article
+//	@Tags		articles
+//	@Produce	json
+//	@Param		id	path		int	true	"Post ID"
+//	@Param		rev	path		int	true	"Version of the revision to restore"
+//	@Success	200	{object}	Response{data=model.Post}
+//	@Failure	400	{object}	Response
+//	@Failure	401	{object}	Response
+//	@Failure	403	{object}	Response
+//	@Failure	404	{object}	Response
+//	@Failure	409	{object}	Response
+//	@Failure	422	{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/{id}/revisions/{rev}/restore [post]
+func (h *PostHandler) RestorePostRevision(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	postID, ok := parseID(context, "post")
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(context.Param("rev"))
+	if err != nil || version < 1 {
+		respondError(context, http.StatusBadRequest, "invalid revision")
+		return
+	}
+
+	current, err := h.posts.GetByID(ctx, postID)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	if !canEdit(context, current) {
+		return
+	}
+
+	post, err := h.posts.Restore(ctx, postID, version)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	h.notifyPublished(&current, post)
+
+	respond(context, http.StatusOK, post, nil)
+}
+
+// BulkPosts deletes or changes the status of several posts at once. Posts
+// whose status cannot move to the requested one are skipped and listed as
+// rejected.
+//
+//	@Summary	Apply an action to several articles
+//	@Tags		articles
+//	@Accept		json
+//	@Produce	json
+//	@Param		request	body		model.BulkRequest	true	"Up to 100 ids and one of delete, publish, draft or trash"
+//	@Success	200		{object}	Response{data=model.BulkResult}
+//	@Failure	400		{object}	Response
+//	@Failure	401		{object}	Response
+//	@Failure	403		{object}	Response
+//	@Failure	413		{object}	Response
+//	@Security	BearerAuth
+//	@Security	ApiKeyAuth
+//	@Router		/article/bulk [post]
+func (h *PostHandler) BulkPosts(context *gin.Context) {
+	ctx, cancel := queryContext(context)
+	defer cancel()
+
+	var request model.BulkRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		respondBindError(context, err)
+		return
+	}
+
+	if request.Action != "delete" && !model.IsValidStatus(request.Action) {
+		respondError(context, http.StatusBadRequest, "Action must be either delete, publish, draft, or trash")
+		return
+	}
+	if len(request.IDs) == 0 {
+		respondError(context, http.StatusBadRequest, "ids must not be empty")
+		return
+	}
+	if len(request.IDs) > maxBulkIDs {
+		respondError(context, http.StatusBadRequest, fmt.Sprintf("at most %d ids can be processed at once", maxBulkIDs))
+		return
+	}
+
+	slices.Sort(request.IDs)
+	ids := slices.Compact(request.IDs)
+
+	result, published, err := h.posts.Bulk(ctx, ids, request.Action)
+	if err != nil {
+		respondDBError(context, err)
+		return
+	}
+	for _, post := range published {
+		h.notifyPublished(nil, post)
+	}
+
+	respond(context, http.StatusOK, result, nil)
+}
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+	"backend-projects/article-api/webhook"
+)
+
+const testAPIKey = "test-key"
+
+// fakePosts is an in-memory repository.PostRepository covering the calls the
+// CRUD handlers make. Every call fails with err when it is set; the methods
+// it does not implement panic through the nil embedded interface.
+type fakePosts struct {
+	repository.PostRepository
+	posts  map[int]model.Post
+	nextID int
+	err    error
+}
+
+func newFakePosts(posts ...model.Post) *fakePosts {
+	fake := &fakePosts{posts: map[int]model.Post{}, nextID: 1}
+	for _, post := range posts {
+		fake.posts[post.ID] = post
+		fake.nextID = max(fake.nextID, post.ID+1)
+	}
+	return fake
+}
+
+func (f *fakePosts) GetAll(_ context.Context, filter model.PostFilter) ([]model.Post, int, error) {
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	posts := []model.Post{}
+	for _, post := range f.posts {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+	return posts, len(posts), nil
+}
+
+func (f *fakePosts) GetByID(_ context.Context, id int) (model.Post, error) {
+	if f.err != nil {
+		return model.Post{}, f.err
+	}
+	post, ok := f.posts[id]
+	if !ok {
+		return model.Post{}, repository.ErrPostNotFound
+	}
+	return post, nil
+}
+
+func (f *fakePosts) Slug(_ context.Context, title string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return strings.ToLower(strings.Join(strings.Fields(title), "-")), nil
+}
+
+func (f *fakePosts) FindDuplicate(context.Context, string) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return 0, repository.ErrPostNotFound
+}
+
+func (f *fakePosts) Create(ctx context.Context, post model.Post) (model.Post, error) {
+	slug, err := f.Slug(ctx, post.Title)
+	if err != nil {
+		return model.Post{}, err
+	}
+	post.ID = f.nextID
+	post.Slug = slug
+	post.Version = 1
+	f.nextID++
+	f.posts[post.ID] = post
+	return post, nil
+}
+
+func (f *fakePosts) Update(_ context.Context, id int, post model.Post) (model.Post, error) {
+	if f.err != nil {
+		return model.Post{}, f.err
+	}
+	current, ok := f.posts[id]
+	if !ok {
+		return model.Post{}, repository.ErrPostNotFound
+	}
+	if post.Version != current.Version {
+		return model.Post{}, repository.ErrVersionConflict
+	}
+	post.ID, post.Slug, post.AuthorID = id, current.Slug, current.AuthorID
+	post.Version++
+	f.posts[id] = post
+	return post, nil
+}
+
+func (f *fakePosts) Patch(_ context.Context, id int, patch model.PostPatch) (model.Post, error) {
+	if f.err != nil {
+		return model.Post{}, f.err
+	}
+	post, ok := f.posts[id]
+	if !ok {
+		return model.Post{}, repository.ErrPostNotFound
+	}
+	if patch.Version != nil && *patch.Version != post.Version {
+		return model.Post{}, repository.ErrVersionConflict
+	}
+	for field, value := range map[*string]*string{&post.Title: patch.Title, &post.Content: patch.Content, &post.Category: patch.Category, &post.Author: patch.Author, &post.Status: patch.Status} {
+		if value != nil {
+			*field = *value
+		}
+	}
+	if patch.Featured != nil {
+		post.Featured = *patch.Featured
+	}
+	post.Version++
+	f.posts[id] = post
+	return post, nil
+}
+
+func (f *fakePosts) Delete(_ context.Context, id int) error {
+	if f.err != nil {
+		return f.err
+	}
+	if _, ok := f.posts[id]; !ok {
+		return repository.ErrPostNotFound
+	}
+	delete(f.posts, id)
+	return nil
+}
+
+// fakeCategories is the repository.CategoryRepository counterpart of
+// fakePosts.
+type fakeCategories struct {
+	repository.CategoryRepository
+	categories map[int]model.Category
+}
+
+func (f *fakeCategories) GetByID(_ context.Context, id int) (model.Category, error) {
+	category, ok := f.categories[id]
+	if !ok {
+		return model.Category{}, repository.ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// newPostRouter routes the post CRUD endpoints as main does, accepting
+// testAPIKey as an admin's key.
+func newPostRouter(posts repository.PostRepository) *gin.Engine {
+	handler := NewPostHandler(posts, &fakeCategories{categories: map[int]model.Category{7: {ID: 7, Name: "golang"}}}, nil)
+	anyRole := RequireRole(RoleAdmin, RoleAuthor)
+
+	router := gin.New()
+	router.Use(Authenticate(nil, []string{testAPIKey}))
+	router.GET("/article", handler.GetPosts)
+	router.GET("/article/:id", handler.GetPostByID)
+	router.POST("/article", anyRole, handler.AddPost)
+	router.PUT("/article/:id", anyRole, handler.UpdatePostByID)
+	router.PATCH("/article/:id", anyRole, handler.PatchPostByID)
+	router.DELETE("/article/:id", RequireRole(RoleAdmin), handler.DeletePostByID)
+	return router
+}
+
+// serve sends a request with the admin API key through router.
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(APIKeyHeader, testAPIKey)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// decodeResponse reads the envelope of recorder's body, leaving data raw.
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder) (json.RawMessage, *ResponseError) {
+	t.Helper()
+	var response struct {
+		Data  json.RawMessage `json:"data"`
+		Error *ResponseError  `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %q: %v", recorder.Body.String(), err)
+	}
+	return response.Data, response.Error
+}
+
+// storedPost is the post the fake repository starts with in each test.
+func storedPost() model.Post {
+	post := validPost()
+	post.ID = 1
+	post.Slug = "a-title-long-enough-to-pass"
+	post.Format = model.FormatHTML
+	post.Version = 1
+	return post
+}
+
+func intPointer(n int) *int { return &n }
+
+// postBody returns post as a JSON request body after edit changes it.
+func postBody(t *testing.T, edit func(*model.Post)) string {
+	t.Helper()
+	post := validPost()
+	if edit != nil {
+		edit(&post)
+	}
+	body, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestPostCRUD(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       func(t *testing.T) string
+		err        error
+		wantStatus int
+		wantType   string
+		// check, when set, inspects the data of a successful response and
+		// the repository afterwards.
+		check func(t *testing.T, data json.RawMessage, posts *fakePosts)
+	}{
+		{
+			name: "list", method: http.MethodGet, path: "/article",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, data json.RawMessage, _ *fakePosts) {
+				var posts []model.Post
+				if err := json.Unmarshal(data, &posts); err != nil || len(posts) != 1 || posts[0].ID != 1 {
+					t.Errorf("listed %s", data)
+				}
+			},
+		},
+		{
+			name: "list with a bad page", method: http.MethodGet, path: "/article?page=0",
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "list failing", method: http.MethodGet, path: "/article", err: dbErr,
+			wantStatus: http.StatusInternalServerError, wantType: ErrorDB,
+		},
+		{
+			name: "get", method: http.MethodGet, path: "/article/1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, data json.RawMessage, _ *fakePosts) {
+				var post model.Post
+				if err := json.Unmarshal(data, &post); err != nil || post.Title != storedPost().Title {
+					t.Errorf("got %s", data)
+				}
+			},
+		},
+		{
+			name: "get with an invalid id", method: http.MethodGet, path: "/article/abc",
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "get a missing post", method: http.MethodGet, path: "/article/99",
+			wantStatus: http.StatusNotFound, wantType: ErrorNotFound,
+		},
+		{
+			name: "get timing out", method: http.MethodGet, path: "/article/1", err: context.DeadlineExceeded,
+			wantStatus: http.StatusGatewayTimeout, wantType: ErrorTimeout,
+		},
+		{
+			name: "create", method: http.MethodPost, path: "/article",
+			body:       func(t *testing.T) string { return postBody(t, nil) },
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, data json.RawMessage, posts *fakePosts) {
+				var post model.Post
+				if err := json.Unmarshal(data, &post); err != nil || post.ID != 2 || post.Slug != "a-title-long-enough-to-pass" {
+					t.Errorf("created %s", data)
+				}
+				if stored, ok := posts.posts[2]; !ok || stored.AuthorID == nil || *stored.AuthorID != apiKeySubject {
+					t.Errorf("stored %+v", stored)
+				}
+			},
+		},
+		{
+			name: "create in a category", method: http.MethodPost, path: "/article",
+			body: func(t *testing.T) string {
+				return postBody(t, func(post *model.Post) { post.Category, post.CategoryID = "", intPointer(7) })
+			},
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if category := posts.posts[2].Category; category != "golang" {
+					t.Errorf("category = %q, want golang", category)
+				}
+			},
+		},
+		{
+			name: "create in a missing category", method: http.MethodPost, path: "/article",
+			body: func(t *testing.T) string {
+				return postBody(t, func(post *model.Post) { post.CategoryID = intPointer(8) })
+			},
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "create with a short title", method: http.MethodPost, path: "/article",
+			body:       func(t *testing.T) string { return postBody(t, func(post *model.Post) { post.Title = "Too short" }) },
+			wantStatus: http.StatusUnprocessableEntity, wantType: ErrorValidationFailed,
+		},
+		{
+			name: "create with malformed JSON", method: http.MethodPost, path: "/article",
+			body:       func(*testing.T) string { return `{"title": ` },
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "create failing", method: http.MethodPost, path: "/article", err: dbErr,
+			body:       func(t *testing.T) string { return postBody(t, nil) },
+			wantStatus: http.StatusInternalServerError, wantType: ErrorDB,
+		},
+		{
+			name: "update", method: http.MethodPut, path: "/article/1",
+			body: func(t *testing.T) string {
+				return postBody(t, func(post *model.Post) { post.Title, post.Version = "A replacement title for the post", 1 })
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if post := posts.posts[1]; post.Title != "A replacement title for the post" || post.Version != 2 {
+					t.Errorf("stored %+v", post)
+				}
+			},
+		},
+		{
+			name: "update without a version", method: http.MethodPut, path: "/article/1",
+			body:       func(t *testing.T) string { return postBody(t, nil) },
+			wantStatus: http.StatusUnprocessableEntity, wantType: ErrorValidationFailed,
+		},
+		{
+			name: "update with a stale version", method: http.MethodPut, path: "/article/1",
+			body:       func(t *testing.T) string { return postBody(t, func(post *model.Post) { post.Version = 5 }) },
+			wantStatus: http.StatusConflict, wantType: ErrorVersionConflict,
+		},
+		{
+			name: "update a missing post", method: http.MethodPut, path: "/article/99",
+			body:       func(t *testing.T) string { return postBody(t, func(post *model.Post) { post.Version = 1 }) },
+			wantStatus: http.StatusNotFound, wantType: ErrorNotFound,
+		},
+		{
+			name: "update failing", method: http.MethodPut, path: "/article/1", err: dbErr,
+			body:       func(t *testing.T) string { return postBody(t, func(post *model.Post) { post.Version = 1 }) },
+			wantStatus: http.StatusInternalServerError, wantType: ErrorDB,
+		},
+		{
+			name: "patch", method: http.MethodPatch, path: "/article/1",
+			body:       func(*testing.T) string { return `{"status": "publish"}` },
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if status := posts.posts[1].Status; status != "publish" {
+					t.Errorf("status = %q, want publish", status)
+				}
+			},
+		},
+		{
+			name: "feature through a patch", method: http.MethodPatch, path: "/article/1",
+			body:       func(*testing.T) string { return `{"featured": true}` },
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if !posts.posts[1].Featured {
+					t.Error("post not featured")
+				}
+			},
+		},
+		{
+			name: "patch nothing", method: http.MethodPatch, path: "/article/1",
+			body:       func(*testing.T) string { return `{}` },
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "patch with an invalid status", method: http.MethodPatch, path: "/article/1",
+			body:       func(*testing.T) string { return `{"status": "archived"}` },
+			wantStatus: http.StatusUnprocessableEntity, wantType: ErrorValidationFailed,
+		},
+		{
+			name: "patch a missing post", method: http.MethodPatch, path: "/article/99",
+			body:       func(*testing.T) string { return `{"status": "publish"}` },
+			wantStatus: http.StatusNotFound, wantType: ErrorNotFound,
+		},
+		{
+			name: "patch failing", method: http.MethodPatch, path: "/article/1", err: dbErr,
+			body:       func(*testing.T) string { return `{"status": "publish"}` },
+			wantStatus: http.StatusInternalServerError, wantType: ErrorDB,
+		},
+		{
+			name: "trash", method: http.MethodDelete, path: "/article/1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if status := posts.posts[1].Status; status != "trash" {
+					t.Errorf("status = %q, want trash", status)
+				}
+			},
+		},
+		{
+			name: "delete permanently", method: http.MethodDelete, path: "/article/1?permanent=true",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, _ json.RawMessage, posts *fakePosts) {
+				if _, ok := posts.posts[1]; ok {
+					t.Error("post still stored")
+				}
+			},
+		},
+		{
+			name: "delete with an invalid id", method: http.MethodDelete, path: "/article/0",
+			wantStatus: http.StatusBadRequest, wantType: ErrorBadRequest,
+		},
+		{
+			name: "delete a missing post", method: http.MethodDelete, path: "/article/99?permanent=true",
+			wantStatus: http.StatusNotFound, wantType: ErrorNotFound,
+		},
+		{
+			name: "delete failing", method: http.MethodDelete, path: "/article/1", err: dbErr,
+			wantStatus: http.StatusInternalServerError, wantType: ErrorDB,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			posts := newFakePosts(storedPost())
+			posts.err = test.err
+			var body string
+			if test.body != nil {
+				body = test.body(t)
+			}
+
+			recorder := serve(newPostRouter(posts), test.method, test.path, body)
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+			data, responseErr := decodeResponse(t, recorder)
+			if test.wantType != "" {
+				if responseErr == nil || responseErr.Type != test.wantType {
+					t.Errorf("error = %+v, want type %s", responseErr, test.wantType)
+				}
+				return
+			}
+			if responseErr != nil {
+				t.Errorf("unexpected error %+v", responseErr)
+			}
+			if test.check != nil {
+				test.check(t, data, posts)
+			}
+		})
+	}
+}
+
+func TestWritesNeedCredentials(t *testing.T) {
+	router := newPostRouter(newFakePosts(storedPost()))
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		path := "/article/1"
+		if method == http.MethodPost {
+			path = "/article"
+		}
+		request := httptest.NewRequest(method, path, strings.NewReader(postBody(t, nil)))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without credentials answered %d, want 401", method, path, recorder.Code)
+		}
+	}
+}
+
+func TestCountsNeedNoCredentials(t *testing.T) {
+	router := gin.New()
+	router.Use(Authenticate(nil, []string{testAPIKey}))
+	counted := func(context *gin.Context) { context.Status(http.StatusOK) }
+	router.POST("/article/:id/view", counted)
+	router.POST("/article/:id/like", counted)
+	router.DELETE("/article/:id/like", counted)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/article/1/view"},
+		{http.MethodPost, "/article/1/like"},
+		{http.MethodDelete, "/article/1/like"},
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(route.method, route.path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s %s without credentials answered %d, want 200", route.method, route.path, recorder.Code)
+		}
+	}
+}
+
+func TestScheduledPostIsNotAnnouncedEarly(t *testing.T) {
+	delivered := make(chan model.Post, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var post model.Post
+		json.NewDecoder(r.Body).Decode(&post)
+		delivered <- post
+	}))
+	defer receiver.Close()
+	webhooks, err := webhook.New([]string{receiver.URL}, nil, time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewPostHandler(newFakePosts(), &fakeCategories{}, webhooks)
+	router := gin.New()
+	router.Use(Authenticate(nil, []string{testAPIKey}))
+	router.POST("/article", handler.AddPost)
+
+	later := time.Now().Add(time.Hour)
+	scheduled := serve(router, http.MethodPost, "/article", postBody(t, func(post *model.Post) {
+		post.Title, post.Status, post.PublishAt = "A post scheduled for later", "publish", &later
+	}))
+	live := serve(router, http.MethodPost, "/article", postBody(t, func(post *model.Post) {
+		post.Title, post.Status = "A post published right away", "publish"
+	}))
+	if scheduled.Code != http.StatusCreated || live.Code != http.StatusCreated {
+		t.Fatalf("creating answered %d and %d, want 201", scheduled.Code, live.Code)
+	}
+
+	select {
+	case post := <-delivered:
+		if post.Title != "A post published right away" {
+			t.Errorf("announced %q, want only the live post", post.Title)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the live post was not announced")
+	}
+	select {
+	case post := <-delivered:
+		t.Errorf("also announced %q", post.Title)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDatabaseErrorIsNotShown(t *testing.T) {
+	posts := newFakePosts(storedPost())
+	posts.err = errors.New("dial tcp 10.0.0.5:3306: connection refused")
+	recorder := serve(newPostRouter(posts), http.MethodGet, "/article/1", "")
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("answered %d, want 500", recorder.Code)
+	}
+	if _, responseErr := decodeResponse(t, recorder); responseErr == nil || responseErr.Message != "internal server error" {
+		t.Errorf("error = %+v, want the generic message", responseErr)
+	}
+}
+
+// slowPosts is a fakePosts whose lookups hang like a stalled database until
+// their context ends.
+type slowPosts struct {
+	*fakePosts
+}
+
+func (slowPosts) GetByID(ctx context.Context, _ int) (model.Post, error) {
+	<-ctx.Done()
+	return model.Post{}, ctx.Err()
+}
+
+func TestSlowQueryTimesOut(t *testing.T) {
+	defer func(timeout time.Duration) { QueryTimeout = timeout }(QueryTimeout)
+	QueryTimeout = 20 * time.Millisecond
+
+	router := newPostRouter(slowPosts{newFakePosts(storedPost())})
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch} {
+		start := time.Now()
+		recorder := serve(router, method, "/article/1", `{"status": "draft"}`)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s took %s, want about %s", method, elapsed, QueryTimeout)
+		}
+		if recorder.Code != http.StatusGatewayTimeout {
+			t.Fatalf("%s answered %d, want 504: %s", method, recorder.Code, recorder.Body)
+		}
+		if _, responseErr := decodeResponse(t, recorder); responseErr == nil || responseErr.Type != ErrorTimeout {
+			t.Errorf("%s error = %+v, want type %s", method, responseErr, ErrorTimeout)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetHealth reports that the process is alive without touching the database.
+//
+//	@Summary	Liveness probe
+//	@Tags		probes
+//	@Produce	json
+//	@Success	200	{object}	Response
+//	@Router		/health [get]
+func GetHealth(context *gin.Context) {
+	respond(context, http.StatusOK, gin.H{"status": "ok"}, nil)
+}
+
+// BuildInfo identifies the running build.
+type BuildInfo struct {
+	Commit string `json:"commit"`
+	// BuildTime is when the binary was built or, when that was not recorded,
+	// when its commit was made.
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	// Modified is set when the build had uncommitted changes.
+	Modified bool `json:"modified"`
+}
+
+// GetVersion reports which build is running.
+//
+//	@Summary	Build information
+//	@Tags		probes
+//	@Produce	json
+//	@Success	200	{object}	Response{data=BuildInfo}
+//	@Router		/version [get]
+func GetVersion(info BuildInfo) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		respond(context, http.StatusOK, info, nil)
+	}
+}
+
+// GetReady reports whether the database is reachable.
+//
+//	@Summary	Readiness probe
+//	@Tags		probes
+//	@Produce	json
+//	@Success	200	{object}	Response
+//	@Failure	503	{object}	Response
+//	@Router		/ready [get]
+func GetReady(db *sql.DB) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		ctx, cancel := queryContext(context)
+		defer cancel()
+
+		if err := db.PingContext(ctx); err != nil {
+			respondError(context, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		respond(context, http.StatusOK, gin.H{"status": "ok"}, nil)
+	}
+}
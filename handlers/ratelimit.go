@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// IPRateLimiter keeps one token bucket per client IP.
+type IPRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	visitors map[string]*visitor
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewIPRateLimiter(limit rate.Limit, burst int) *IPRateLimiter {
+	return &IPRateLimiter{limit: limit, burst: burst, visitors: map[string]*visitor{}}
+}
+
+func (l *IPRateLimiter) limiter(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, ok := l.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+// EvictIdle forgets clients not seen for idle, so the map does not grow
+// without bound. It returns when ctx is done.
+func (l *IPRateLimiter) EvictIdle(ctx context.Context, idle time.Duration) {
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			for ip, v := range l.visitors {
+				if time.Since(v.lastSeen) > idle {
+					delete(l.visitors, ip)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// Middleware answers 429 with a Retry-After header once a client has used up
+// its burst.
+func (l *IPRateLimiter) Middleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		reservation := l.limiter(context.ClientIP()).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			context.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			respondError(context, http.StatusTooManyRequests, "too many requests")
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
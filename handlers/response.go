@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"backend-projects/article-api/repository"
+)
+
+// Response is the envelope every endpoint answers with. Exactly one of Data
+// and Error is set; Meta carries extra information such as pagination.
+type Response struct {
+	Data  any            `json:"data"`
+	Error *ResponseError `json:"error"`
+	Meta  any            `json:"meta,omitempty"`
+}
+
+type ResponseError struct {
+	// Code is the HTTP status.
+	Code int `json:"code"`
+	// Type is one of the Error constants. Clients should tell failures
+	// apart by it rather than by Message, which may be reworded.
+	Type    string `json:"type" example:"NOT_FOUND"`
+	Message string `json:"message"`
+	// Errors lists every invalid field when a request body fails validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// The error types a ResponseError can carry. They are part of the API and
+// never change once released.
+const (
+	ErrorBadRequest           = "BAD_REQUEST"
+	ErrorUnauthorized         = "UNAUTHORIZED"
+	ErrorForbidden            = "FORBIDDEN"
+	ErrorNotFound             = "NOT_FOUND"
+	ErrorMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	ErrorConflict             = "CONFLICT"
+	ErrorVersionConflict      = "VERSION_CONFLICT"
+	ErrorDuplicateTitle       = "DUPLICATE_TITLE"
+	ErrorDuplicateSlug        = "DUPLICATE_SLUG"
+	ErrorDuplicateContent     = "DUPLICATE_CONTENT"
+	ErrorCategoryInUse        = "CATEGORY_IN_USE"
+	ErrorCategoryExists       = "CATEGORY_EXISTS"
+	ErrorIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	ErrorPayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrorUnsupportedMedia     = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorValidationFailed     = "VALIDATION_FAILED"
+	ErrorRateLimited          = "RATE_LIMITED"
+	ErrorInternal             = "INTERNAL_ERROR"
+	ErrorDB                   = "DB_ERROR"
+	ErrorUnavailable          = "UNAVAILABLE"
+	ErrorTimeout              = "TIMEOUT"
+)
+
+// statusErrorTypes is the type respondError gives each status.
+var statusErrorTypes = map[int]string{
+	http.StatusBadRequest:            ErrorBadRequest,
+	http.StatusUnauthorized:          ErrorUnauthorized,
+	http.StatusForbidden:             ErrorForbidden,
+	http.StatusNotFound:              ErrorNotFound,
+	http.StatusMethodNotAllowed:      ErrorMethodNotAllowed,
+	http.StatusConflict:              ErrorConflict,
+	http.StatusRequestEntityTooLarge: ErrorPayloadTooLarge,
+	http.StatusUnsupportedMediaType:  ErrorUnsupportedMedia,
+	http.StatusUnprocessableEntity:   ErrorValidationFailed,
+	http.StatusTooManyRequests:       ErrorRateLimited,
+	http.StatusInternalServerError:   ErrorInternal,
+	http.StatusServiceUnavailable:    ErrorUnavailable,
+	http.StatusGatewayTimeout:        ErrorTimeout,
+}
+
+// FieldError is one failed validation rule. Field is the JSON name of the
+// offending field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// queryContext derives a context for database calls from the request context,
+// so a slow query is cancelled after QueryTimeout or when the client goes away.
+func queryContext(ginContext *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ginContext.Request.Context(), QueryTimeout)
+}
+
+func respond(context *gin.Context, status int, data any, meta any) {
+	context.IndentedJSON(status, Response{Data: data, Meta: meta})
+}
+
+// respondError answers with the error type that goes with status; use
+// respondErrorType when the failure deserves a more specific one.
+func respondError(context *gin.Context, status int, message string) {
+	respondErrorType(context, status, statusErrorTypes[status], message)
+}
+
+func respondErrorType(context *gin.Context, status int, errorType, message string) {
+	context.IndentedJSON(status, Response{Error: &ResponseError{Code: status, Type: errorType, Message: message}})
+}
+
+// NoRoute answers requests for paths no route matches.
+func NoRoute(context *gin.Context) {
+	respondError(context, http.StatusNotFound, "route not found")
+}
+
+// NoMethod answers requests whose path matches a route but not its method.
+// The router has already listed the methods that path accepts in the Allow
+// header.
+func NoMethod(context *gin.Context) {
+	respondError(context, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", context.Request.Method))
+}
+
+// respondDBError reports a failed repository call, answering 404 for a
+// missing post, 422 for a status change that is not allowed, 504 when the
+// query ran out of time and 500 otherwise.
+func respondDBError(ginContext *gin.Context, err error) {
+	var transitionErr *repository.TransitionError
+	if errors.As(err, &transitionErr) {
+		respondInvalid(ginContext, []FieldError{{Field: "status", Message: fmt.Sprintf("Status cannot change from %s to %s", transitionErr.From, transitionErr.To)}})
+		return
+	}
+	status, errorType, message := dbErrorStatus(err)
+	respondErrorType(ginContext, status, errorType, message)
+}
+
+// dbErrorStatus returns the status, error type and message respondDBError
+// reports err with. An unexpected error is logged rather than shown, as its
+// text can describe the database.
+func dbErrorStatus(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, repository.ErrPostNotFound):
+		return http.StatusNotFound, ErrorNotFound, "post not found"
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return http.StatusNotFound, ErrorNotFound, "category not found"
+	case errors.Is(err, repository.ErrRevisionNotFound):
+		return http.StatusNotFound, ErrorNotFound, "revision not found"
+	case errors.Is(err, repository.ErrCategoryExists):
+		return http.StatusConflict, ErrorCategoryExists, "a category with this name already exists"
+	case errors.Is(err, repository.ErrCategoryInUse):
+		return http.StatusConflict, ErrorCategoryInUse, "category is still used by posts"
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusConflict, ErrorVersionConflict, "post was modified by someone else; refetch it and try again"
+	case errors.Is(err, repository.ErrDuplicateTitle):
+		return http.StatusConflict, ErrorDuplicateTitle, "an article with this title already exists"
+	case errors.Is(err, repository.ErrDuplicateSlug):
+		return http.StatusConflict, ErrorDuplicateSlug, "another article took this slug at the same time; try again"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, ErrorTimeout, "database query timed out"
+	default:
+		slog.Error("database error", "error", err)
+		return http.StatusInternalServerError, ErrorDB, "internal server error"
+	}
+}
+
+// respondBindError answers a request body that could not be read, decoded or
+// validated: 413 when it is larger than LimitBody allows, 422 listing every
+// invalid field when it fails validation and 400 otherwise.
+func respondBindError(ginContext *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondError(ginContext, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must be at most %d bytes", maxBytesErr.Limit))
+		return
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		respondInvalid(ginContext, fieldErrors(validationErrs))
+		return
+	}
+	message := decodeMessage(err, "request body")
+	if message == "" {
+		// Other errors describe the decoder rather than the body.
+		message = "request body could not be decoded"
+	}
+	respondError(ginContext, http.StatusBadRequest, message)
+}
+
+// respondInvalid answers 422 with every field that failed validation, so a
+// client can flag them all at once.
+func respondInvalid(context *gin.Context, fields []FieldError) {
+	context.IndentedJSON(http.StatusUnprocessableEntity, Response{Error: &ResponseError{Code: http.StatusUnprocessableEntity, Type: ErrorValidationFailed, Message: "validation failed", Errors: fields}})
+}
@@ -1,33 +1,122 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"strconv"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.org/x/time/rate"
+
+	_ "backend-projects/article-api/docs"
+	"backend-projects/article-api/handlers"
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+	"backend-projects/article-api/storage"
+	"backend-projects/article-api/webhook"
 )
 
-type Post struct {
-	ID       int    `json:"id"`
-	Title    string `json:"title"`
-	Content  string `json:"content"`
-	Category string `json:"category"`
-	Status   string `json:"status"`
-}
+// shutdownTimeout bounds how long in-flight requests get to finish once
+// the process is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// The general API information below feeds the OpenAPI spec in docs/, which
+// is regenerated with `swag init -g main.go -d ./,./handlers,./model`.
+//
+//	@title						Article API
+//	@version					1.0
+//	@description				A small CRUD API for articles.
+//	@host						localhost:8080
+//	@BasePath					/
+//
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				A JWT signed with JWT_SECRET, sent as "Bearer <token>".
+//
+//	@securityDefinitions.apikey	ApiKeyAuth
+//	@in							header
+//	@name						X-API-Key
+//	@description				One of the keys in API_KEYS.
+func main() {
+	// The .env file is optional; deployments may set the variables directly.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("loading .env file: %v", err)
+	}
 
-var db *sql.DB
+	appEnv := envString("APP_ENV", "production")
+	if appEnv != "production" && appEnv != "development" {
+		log.Fatalf("invalid APP_ENV %q: must be production or development", appEnv)
+	}
+	// Setting the default logger also sends what the log package writes
+	// through it. Only startup failures still use that package.
+	logger := newLogger(appEnv == "production")
+	slog.SetDefault(logger)
+	slog.SetLogLoggerLevel(slog.LevelError)
+	if appEnv == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("error loading .env file")
+	var jwtSecret []byte
+	if secret := envString("JWT_SECRET", ""); secret != "" {
+		jwtSecret = []byte(secret)
+	}
+	apiKeys := envList("API_KEYS", nil)
+	webhookURLs := envList("WEBHOOK_URLS", nil)
+	webhookSecret := envString("WEBHOOK_SECRET", "")
+
+	// Checking these up front reports every missing setting at once, rather
+	// than letting the first database call fail without saying why.
+	missing := missingEnv("DB_USERNAME", "DB_HOST", "DB_PORT", "DB_NAME")
+	if jwtSecret == nil && len(apiKeys) == 0 {
+		missing = append(missing, "JWT_SECRET or API_KEYS")
+	}
+	if len(webhookURLs) > 0 && webhookSecret == "" {
+		missing = append(missing, "WEBHOOK_SECRET")
+	}
+	// TLS is optional, but a certificate without its key or the other way
+	// round is a mistake rather than a request for plain HTTP.
+	certFile := envString("TLS_CERT_FILE", "")
+	keyFile := envString("TLS_KEY_FILE", "")
+	if certFile != "" && keyFile == "" {
+		missing = append(missing, "TLS_KEY_FILE")
+	}
+	if keyFile != "" && certFile == "" {
+		missing = append(missing, "TLS_CERT_FILE")
+	}
+	if len(missing) > 0 {
+		log.Fatalf("missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+	useTLS := certFile != ""
+	if useTLS {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			log.Fatalf("loading TLS certificate: %v", err)
+		}
 	}
 
 	username := os.Getenv("DB_USERNAME")
@@ -36,189 +125,374 @@ func main() {
 	port := os.Getenv("DB_PORT")
 	dbname := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", username, password, host, port, dbname)
-	db, err = sql.Open("mysql", dsn)
-	if err != nil {
-		panic(err)
+	if err := handlers.RegisterValidators(); err != nil {
+		log.Fatalf("registering validators: %v", err)
 	}
-	defer db.Close()
 
-	err = db.Ping()
-	if err != nil {
-		log.Fatal(err)
+	handlers.QueryTimeout = envDuration("DB_QUERY_TIMEOUT", handlers.QueryTimeout)
+	handlers.PublicBaseURL = strings.TrimRight(envString("PUBLIC_BASE_URL", handlers.PublicBaseURL), "/")
+	handlers.FeedSize = envInt("FEED_SIZE", handlers.FeedSize)
+	handlers.DefaultPageSize = envInt("DEFAULT_PAGE_SIZE", handlers.DefaultPageSize)
+	handlers.MaxPageSize = envInt("MAX_PAGE_SIZE", handlers.MaxPageSize)
+	if handlers.DefaultPageSize < 1 || handlers.MaxPageSize < handlers.DefaultPageSize {
+		log.Fatalf("invalid page sizes: default=%d max=%d", handlers.DefaultPageSize, handlers.MaxPageSize)
 	}
-
-	router := gin.Default()
-
-	router.Use(cors.Default())
-
-	router.GET("/article", getPosts)
-	router.GET("/article/:id", getPostById)
-	router.PUT("/article/:id", updatePostById)
-	router.POST("/article", addPost)
-	router.DELETE("/article/:id", deletePostById)
-
-	router.Run("localhost:8080")
-}
-
-func getPosts(context *gin.Context) {
-	rows, err := db.Query("SELECT id, title, content, category, status status FROM posts")
-	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	handlers.ListCacheMaxAge = envDuration("LIST_CACHE_MAX_AGE", handlers.ListCacheMaxAge)
+	handlers.PostCacheMaxAge = envDuration("ARTICLE_CACHE_MAX_AGE", handlers.PostCacheMaxAge)
+	if handlers.ListCacheMaxAge < 0 || handlers.PostCacheMaxAge < 0 {
+		log.Fatalf("invalid cache max ages: list=%s article=%s", handlers.ListCacheMaxAge, handlers.PostCacheMaxAge)
 	}
-	defer rows.Close()
-
-	var posts []Post
-	for rows.Next() {
-		var post Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.Status); err != nil {
-			context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		posts = append(posts, post)
+	handlers.DuplicateContent = envString("DUPLICATE_CONTENT", handlers.DuplicateContent)
+	if !slices.Contains([]string{handlers.DuplicateAllow, handlers.DuplicateWarn, handlers.DuplicateReject}, handlers.DuplicateContent) {
+		log.Fatalf("invalid DUPLICATE_CONTENT %q: must be allow, warn or reject", handlers.DuplicateContent)
 	}
-
-	context.IndentedJSON(http.StatusOK, posts)
-}
-
-func getPostById(context *gin.Context) {
-	id := context.Param("id")
-	var post Post
-
-	err := db.QueryRow("SELECT id, title, content, category, status FROM posts WHERE id = ?", id).Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.Status)
+	// Suffixed slugs must still fit the 255 characters of the slug column.
+	model.MaxSlugLength = envInt("SLUG_MAX_LENGTH", model.MaxSlugLength)
+	if model.MaxSlugLength < 10 || model.MaxSlugLength > 255 {
+		log.Fatalf("invalid SLUG_MAX_LENGTH %d: must be between 10 and 255", model.MaxSlugLength)
+	}
+	model.ReservedSlugs = envList("RESERVED_SLUGS", model.ReservedSlugs)
+	contentPolicy, err := model.SanitizePolicy(envString("CONTENT_POLICY", "ugc"))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			context.IndentedJSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		} else {
-			context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Fatalf("invalid CONTENT_POLICY: %v", err)
+	}
+	model.ContentPolicy = contentPolicy
+	if envBool("BLOCKLIST_ENABLED", false) {
+		terms := envList("BLOCKLIST_TERMS", nil)
+		if path := envString("BLOCKLIST_FILE", ""); path != "" {
+			fileTerms, err := model.LoadBlocklist(path)
+			if err != nil {
+				log.Fatalf("loading blocklist: %v", err)
+			}
+			terms = append(terms, fileTerms...)
+		}
+		model.BannedTerms = model.NewBlocklist(terms)
+		if model.BannedTerms.Len() == 0 {
+			log.Fatal("BLOCKLIST_ENABLED is set but neither BLOCKLIST_TERMS nor BLOCKLIST_FILE lists any terms")
 		}
-		return
+		slog.Info("blocklist loaded", "terms", model.BannedTerms.Len())
 	}
 
-	context.IndentedJSON(http.StatusOK, post)
-}
-
-func addPost(context *gin.Context) {
-	var newPost Post
-	if err := context.BindJSON(&newPost); err != nil {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	var sqlDialect repository.Dialect
+	// migrationURL addresses the same database in the form golang-migrate
+	// expects.
+	var dsn, migrationURL string
+	driver := envString("DB_DRIVER", "mysql")
+	switch driver {
+	case "mysql":
+		sqlDialect = repository.MySQLDialect{}
+		// clientFoundRows makes UPDATE report matched rather than changed
+		// rows, so an update that happens to change nothing is not mistaken
+		// for a missing post.
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&clientFoundRows=true", username, password, host, port, dbname)
+		// Migration files may hold several statements.
+		migrationURL = "mysql://" + dsn + "&multiStatements=true"
+	case "postgres":
+		sqlDialect = repository.PostgresDialect{}
+		postgresURL := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(username, password),
+			Host:     net.JoinHostPort(host, port),
+			Path:     dbname,
+			RawQuery: url.Values{"sslmode": {envString("DB_SSLMODE", "disable")}}.Encode(),
+		}
+		dsn = postgresURL.String()
+		postgresURL.Scheme = "pgx5"
+		migrationURL = postgresURL.String()
+	default:
+		log.Fatalf("invalid DB_DRIVER %q: must be mysql or postgres", driver)
 	}
 
-	if newPost.Title == "" || newPost.Content == "" || newPost.Category == "" || newPost.Status == "" {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "missing or invalid input"})
-		return
-	}
-	if len(newPost.Title) < 20 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 20 characters"})
-		return
-	}
-	if len(newPost.Content) < 200 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Content must be at least 200 characters"})
-		return
+	sqlDB, err := sql.Open(sqlDialect.DriverName(), dsn)
+	if err != nil {
+		panic(err)
 	}
-	if len(newPost.Category) < 3 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Category must be at least 3 characters"})
-		return
+	defer sqlDB.Close()
+	db := repository.NewDatabase(sqlDB, sqlDialect)
+
+	maxOpenConns := envInt("DB_MAX_OPEN_CONNS", 25)
+	maxIdleConns := envInt("DB_MAX_IDLE_CONNS", 25)
+	connMaxLifetime := envDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute)
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+	slog.Info("db pool", "max_open_conns", maxOpenConns, "max_idle_conns", maxIdleConns, "conn_max_lifetime", connMaxLifetime)
+
+	connectAttempts := envInt("DB_CONNECT_ATTEMPTS", 10)
+	connectTimeout := envDuration("DB_CONNECT_TIMEOUT", time.Minute)
+	if connectAttempts < 1 || connectTimeout <= 0 {
+		log.Fatalf("invalid database connect settings: attempts=%d timeout=%s", connectAttempts, connectTimeout)
 	}
-	if newPost.Status != "publish" && newPost.Status != "draft" && newPost.Status != "trash" {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Status must be either publish, draft, or trash"})
-		return
+	if err := pingDatabase(db, connectAttempts, connectTimeout); err != nil {
+		log.Fatalf("connecting to database: %v", err)
 	}
 
-	result, err := db.Exec("INSERT INTO posts (title, content, category, status) VALUES (?, ?, ?, ?)", newPost.Title, newPost.Content, newPost.Category, newPost.Status)
-	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if envBool("RUN_MIGRATIONS", false) {
+		if err := runMigrations(driver, migrationURL); err != nil {
+			log.Fatalf("running migrations: %v", err)
+		}
 	}
 
-	id, err := result.LastInsertId()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(sqlDB, dbname),
+	)
+
+	sqlPosts := repository.NewSQLPostRepository(db)
+	var postStore repository.PostRepository = sqlPosts
+	var categoryStore repository.CategoryRepository = repository.NewSQLCategoryRepository(db)
+	if envBool("POST_CACHE_ENABLED", true) {
+		cacheSize := envInt("POST_CACHE_SIZE", 1000)
+		cacheTTL := envDuration("POST_CACHE_TTL", time.Minute)
+		if cacheSize < 1 || cacheTTL <= 0 {
+			log.Fatalf("invalid post cache settings: size=%d ttl=%s", cacheSize, cacheTTL)
+		}
+		cache := repository.NewPostCache(cacheSize, cacheTTL, registry)
+		postStore = repository.NewCachedPostRepository(postStore, cache)
+		categoryStore = repository.NewCachedCategoryRepository(categoryStore, cache)
+	}
+	webhooks, err := webhook.New(webhookURLs, []byte(webhookSecret), envDuration("WEBHOOK_TIMEOUT", 5*time.Second), envInt("WEBHOOK_RETRIES", 3))
 	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		log.Fatalf("configuring webhooks: %v", err)
 	}
+	posts := handlers.NewPostHandler(postStore, categoryStore, webhooks)
+	categories := handlers.NewCategoryHandler(categoryStore, posts)
 
-	newPost.ID = int(id)
-	context.JSON(http.StatusCreated, newPost)
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-func updatePostById(context *gin.Context) {
-	id := context.Param("id")
-
-	postID, err := strconv.Atoi(id)
-	if err != nil {
-		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid post ID"})
-		return
+	publishInterval := envDuration("PUBLISH_INTERVAL", time.Minute)
+	if publishInterval <= 0 {
+		log.Fatalf("invalid PUBLISH_INTERVAL: %s", publishInterval)
 	}
-
-	var updatedPost Post
-	if err := context.BindJSON(&updatedPost); err != nil {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	go publishScheduled(ctx, postStore, webhooks, publishInterval)
+	go func() {
+		if err := sqlPosts.CountWords(ctx); err != nil {
+			slog.Error("counting words of existing posts", "error", err)
+		}
+		if err := sqlPosts.HashContent(ctx); err != nil {
+			slog.Error("hashing content of existing posts", "error", err)
+		}
+	}()
+
+	router := gin.New()
+	// Without this a known path requested with the wrong method is a 404.
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.NoRoute)
+	router.NoMethod(handlers.NoMethod)
+
+	// Metrics sit outside Recovery so a panic is still counted as the 500
+	// it turns into.
+	metrics := handlers.NewHTTPMetrics(registry)
+	router.Use(handlers.RequestLogger(logger), metrics.Middleware(), metrics.Recovery())
+
+	// Probes are registered before the CORS middleware so they stay outside
+	// of it.
+	router.GET("/health", handlers.GetHealth)
+	router.GET("/ready", handlers.GetReady(sqlDB))
+	router.GET("/version", handlers.GetVersion(buildInfo()))
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	gzipLevel := envInt("GZIP_LEVEL", gzip.DefaultCompression)
+	if gzipLevel < gzip.HuffmanOnly || gzipLevel > gzip.BestCompression {
+		log.Fatalf("invalid GZIP_LEVEL: %d", gzipLevel)
 	}
+	gzipMinSize := envInt("GZIP_MIN_SIZE", 1024)
 
-	if updatedPost.Title == "" || updatedPost.Content == "" || updatedPost.Category == "" || updatedPost.Status == "" {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "missing or invalid input"})
-		return
+	corsSettings := corsConfig()
+	if err := corsSettings.Validate(); err != nil {
+		log.Fatalf("invalid CORS configuration: %v", err)
 	}
-	if len(updatedPost.Title) < 20 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 20 characters"})
-		return
-	}
-	if len(updatedPost.Content) < 200 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Content must be at least 200 characters"})
-		return
+
+	maxBodyBytes := envInt("MAX_BODY_BYTES", 1<<20)
+	if maxBodyBytes < 1 {
+		log.Fatalf("invalid MAX_BODY_BYTES: %d", maxBodyBytes)
 	}
-	if len(updatedPost.Category) < 3 {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Category must be at least 3 characters"})
-		return
+	coverMaxBytes := envInt("COVER_MAX_BYTES", 5<<20)
+	if coverMaxBytes < 1 {
+		log.Fatalf("invalid COVER_MAX_BYTES: %d", coverMaxBytes)
 	}
-	if updatedPost.Status != "publish" && updatedPost.Status != "draft" && updatedPost.Status != "trash" {
-		context.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Status must be either publish, draft, or trash"})
-		return
+	// Cover uploads may be larger than other bodies; the extra room is for
+	// the multipart framing around the file.
+	bodyLimits := map[string]int64{"/article/:id/cover": int64(coverMaxBytes) + 64<<10}
+
+	handlerTimeout := envDuration("HANDLER_TIMEOUT", 20*time.Second)
+	exportTimeout := envDuration("EXPORT_TIMEOUT", 10*time.Minute)
+	if handlerTimeout <= 0 || exportTimeout <= 0 {
+		log.Fatalf("invalid handler timeouts: handler=%s export=%s", handlerTimeout, exportTimeout)
 	}
+	timeouts := map[string]time.Duration{"/article/export": exportTimeout}
 
-	result, err := db.Exec("UPDATE posts SET title = ?, content = ?, category = ?, status = ? WHERE id = ?", updatedPost.Title, updatedPost.Content, updatedPost.Category, updatedPost.Status, postID)
-	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	router.Use(cors.New(corsSettings), handlers.Timeout(handlerTimeout, timeouts), handlers.GzipResponses(gzipLevel, gzipMinSize), handlers.LimitBody(int64(maxBodyBytes), bodyLimits))
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	// The limiter comes after the probes so they are never throttled.
+	if rateLimit := envFloat("RATE_LIMIT_RPS", 10); rateLimit > 0 {
+		limiter := handlers.NewIPRateLimiter(rate.Limit(rateLimit), envInt("RATE_LIMIT_BURST", 20))
+		go limiter.EvictIdle(ctx, 10*time.Minute)
+		router.Use(limiter.Middleware())
 	}
 
-	if rowsAffected == 0 {
-		context.IndentedJSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		return
+	router.Use(handlers.Authenticate(jwtSecret, apiKeys))
+
+	var coverStore storage.CoverStore
+	switch kind := envString("COVER_STORAGE", "local"); kind {
+	case "local":
+		coverDir := envString("COVER_DIR", "uploads/covers")
+		coverStore = storage.NewLocalCoverStore(coverDir, handlers.PublicBaseURL+"/covers")
+		router.Static("/covers", coverDir)
+	case "s3":
+		coverStore, err = storage.NewS3CoverStore(ctx, envString("COVER_S3_BUCKET", ""), envString("COVER_S3_PREFIX", "covers/"), envString("COVER_S3_BASE_URL", ""))
+		if err != nil {
+			log.Fatalf("configuring S3 cover storage: %v", err)
+		}
+	default:
+		log.Fatalf("invalid COVER_STORAGE %q: must be local or s3", kind)
+	}
+	covers := handlers.NewCoverHandler(postStore, coverStore, int64(coverMaxBytes))
+
+	idempotency := handlers.NewIdempotencyStore(envDuration("IDEMPOTENCY_TTL", 24*time.Hour))
+	go idempotency.EvictExpired(ctx, 10*time.Minute)
+	routeAPI(router, posts, categories, covers, idempotency)
+
+	// Without these a client could hold a connection open indefinitely by
+	// sending its request or reading the response slowly. Reads get enough
+	// time for a cover upload at the largest allowed size.
+	server := &http.Server{
+		Addr:              "localhost:8080",
+		Handler:           router,
+		ReadHeaderTimeout: envDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       envDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+	}
+	if server.ReadHeaderTimeout <= 0 || server.ReadTimeout <= 0 || server.WriteTimeout <= 0 || server.IdleTimeout <= 0 {
+		log.Fatalf("invalid HTTP timeouts: read_header=%s read=%s write=%s idle=%s", server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
 	}
 
-	updatedPost.ID = int(postID)
-	context.IndentedJSON(http.StatusOK, updatedPost)
-}
+	go func() {
+		var err error
+		if useTLS {
+			// Serving TLS also enables HTTP/2 for clients that offer it.
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
 
-func deletePostById(context *gin.Context) {
-	id := context.Param("id")
+	<-ctx.Done()
 
-	result, err := db.Exec("DELETE FROM posts WHERE id = ?", id)
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	slog.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown", "error", err)
 	}
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		context.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// pingDatabase pings db until it answers, waiting between attempts from
+// half a second up to ten, doubling each time, so the API can start
+// alongside a database that is still coming up. It gives up after attempts
+// pings or once timeout has passed, whichever comes first.
+func pingDatabase(db *repository.Database, attempts int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("database not ready", "attempt", attempt, "attempts", attempts, "error", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("giving up after %s: %w", timeout, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 10*time.Second)
 	}
+}
 
-	if rowsAffected == 0 {
-		context.IndentedJSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		return
-	}
+// routeAPI registers the article and category endpoints on router.
+func routeAPI(router gin.IRouter, posts *handlers.PostHandler, categories *handlers.CategoryHandler, covers *handlers.CoverHandler, idempotency *handlers.IdempotencyStore) {
+	router.GET("/article", posts.GetPosts)
+	router.GET("/article/count", posts.CountPosts)
+	router.GET("/article/random", posts.GetRandomPost)
+	router.GET("/article/suggest", posts.SuggestPosts)
+	router.GET("/article/archive", posts.GetArchive)
+	router.GET("/article/:id", posts.GetPostByID)
+	router.GET("/article/slug/:slug", posts.GetPostBySlug)
+	// Authors may only change their own articles, which the handlers check
+	// once they have loaded the article.
+	anyRole := handlers.RequireRole(handlers.RoleAdmin, handlers.RoleAuthor)
+	adminOnly := handlers.RequireRole(handlers.RoleAdmin)
+
+	router.PUT("/article/:id", anyRole, posts.UpdatePostByID)
+	router.POST("/article", anyRole, idempotency.Middleware(), posts.AddPost)
+	router.POST("/article/validate", anyRole, posts.ValidatePost)
+	router.POST("/article/batch", anyRole, posts.BatchCreatePosts)
+	router.POST("/article/import", anyRole, posts.ImportPosts)
+	router.GET("/article/export", adminOnly, posts.ExportPosts)
+	router.PATCH("/article/:id", anyRole, posts.PatchPostByID)
+	router.DELETE("/article/:id", adminOnly, posts.DeletePostByID)
+	router.POST("/article/:id/restore", anyRole, posts.RestorePostByID)
+	router.POST("/article/:id/view", posts.ViewPostByID)
+	router.POST("/article/:id/like", posts.LikePostByID)
+	router.DELETE("/article/:id/like", posts.UnlikePostByID)
+	router.POST("/article/:id/cover", anyRole, covers.UploadCover)
+	router.GET("/article/:id/related", posts.GetRelatedPosts)
+	router.GET("/article/:id/plaintext", posts.GetPostPlainText)
+	router.GET("/article/:id/next", posts.GetNextPost)
+	router.GET("/article/:id/prev", posts.GetPreviousPost)
+	router.GET("/article/:id/revisions", posts.GetPostRevisions)
+	router.POST("/article/:id/revisions/:rev/restore", anyRole, posts.RestorePostRevision)
+	router.POST("/article/bulk", adminOnly, posts.BulkPosts)
+	router.GET("/feed.xml", posts.GetFeed)
+
+	router.GET("/stats", adminOnly, posts.GetStats)
+
+	router.GET("/category", categories.GetCategories)
+	router.GET("/category/:id", categories.GetCategoryByID)
+	router.GET("/category/:id/articles", categories.GetCategoryPosts)
+	router.POST("/category", adminOnly, categories.AddCategory)
+	router.PUT("/category/:id", adminOnly, categories.UpdateCategoryByID)
+	router.DELETE("/category/:id", adminOnly, categories.DeleteCategoryByID)
+}
 
-	context.JSON(http.StatusOK, gin.H{"message": "post deleted"})
+// publishScheduled publishes due scheduled drafts every interval until ctx is
+// done, sending the publish webhook for each, and for each published post
+// whose publish_at has come.
+func publishScheduled(ctx context.Context, posts repository.PostRepository, webhooks *webhook.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			queryCtx, cancel := context.WithTimeout(ctx, handlers.QueryTimeout)
+			published, err := posts.PublishDue(queryCtx)
+			cancel()
+			if err != nil {
+				slog.Error("publishing scheduled posts", "error", err)
+				continue
+			}
+			for _, post := range published {
+				webhooks.Notify(webhook.EventPublished, post)
+			}
+			if len(published) > 0 {
+				slog.Info("published scheduled posts", "count", len(published))
+			}
+		}
+	}
 }
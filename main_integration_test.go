@@ -0,0 +1,271 @@
+//go:build integration
+
+// The integration tests run the API against a real MySQL started with
+// testcontainers, so they need Docker:
+//
+//	go test -tags integration .
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+
+	"backend-projects/article-api/handlers"
+	"backend-projects/article-api/model"
+	"backend-projects/article-api/repository"
+	"backend-projects/article-api/storage"
+)
+
+const integrationAPIKey = "integration-key"
+
+// integrationDB is the migrated database the tests share.
+var integrationDB *repository.Database
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	ctx := context.Background()
+	container, err := mysql.Run(ctx, "mysql:8.0", mysql.WithDatabase("articles"), mysql.WithUsername("articles"), mysql.WithPassword("articles"))
+	if err != nil {
+		log.Printf("starting MySQL: %v", err)
+		return 1
+	}
+	defer container.Terminate(ctx)
+
+	// The same options main connects and migrates with.
+	dsn, err := container.ConnectionString(ctx, "parseTime=true", "clientFoundRows=true")
+	if err != nil {
+		log.Printf("reading the MySQL address: %v", err)
+		return 1
+	}
+	if err := runMigrations("mysql", "mysql://"+dsn+"&multiStatements=true"); err != nil {
+		log.Printf("running migrations: %v", err)
+		return 1
+	}
+	sqlDB, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Printf("opening MySQL: %v", err)
+		return 1
+	}
+	defer sqlDB.Close()
+	integrationDB = repository.NewDatabase(sqlDB, repository.MySQLDialect{})
+
+	gin.SetMode(gin.TestMode)
+	if err := handlers.RegisterValidators(); err != nil {
+		log.Printf("registering validators: %v", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// apiClient sends requests with an admin API key through the routes main
+// serves, backed by integrationDB.
+type apiClient struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func newAPIClient(t *testing.T) *apiClient {
+	t.Helper()
+	postStore := repository.NewSQLPostRepository(integrationDB)
+	categoryStore := repository.NewSQLCategoryRepository(integrationDB)
+	posts := handlers.NewPostHandler(postStore, categoryStore, nil)
+	categories := handlers.NewCategoryHandler(categoryStore, posts)
+	covers := handlers.NewCoverHandler(postStore, storage.NewLocalCoverStore(t.TempDir(), handlers.PublicBaseURL+"/covers"), 1<<20)
+
+	router := gin.New()
+	router.Use(handlers.Authenticate(nil, []string{integrationAPIKey}))
+	routeAPI(router, posts, categories, covers, handlers.NewIdempotencyStore(time.Hour))
+	return &apiClient{t: t, router: router}
+}
+
+// do sends body, encoded as JSON unless nil, and decodes the data of the
+// response into data when it is not nil. It returns the status and the
+// error type of the response.
+func (c *apiClient) do(method, path string, body, data any) (int, string) {
+	c.t.Helper()
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(handlers.APIKeyHeader, integrationAPIKey)
+	recorder := httptest.NewRecorder()
+	c.router.ServeHTTP(recorder, request)
+
+	var response struct {
+		Data  json.RawMessage         `json:"data"`
+		Error *handlers.ResponseError `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		c.t.Fatalf("%s %s: decoding %q: %v", method, path, recorder.Body, err)
+	}
+	if response.Error != nil {
+		return recorder.Code, response.Error.Type
+	}
+	if data != nil {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			c.t.Fatalf("%s %s: decoding data %s: %v", method, path, response.Data, err)
+		}
+	}
+	return recorder.Code, ""
+}
+
+// expect fails the test unless the request answers status.
+func (c *apiClient) expect(status int, method, path string, body, data any) {
+	c.t.Helper()
+	if got, errorType := c.do(method, path, body, data); got != status {
+		c.t.Fatalf("%s %s answered %d %s, want %d", method, path, got, errorType, status)
+	}
+}
+
+// newPost returns a valid post whose title and content are unique to the
+// test.
+func newPost(t *testing.T) model.Post {
+	name := strings.ReplaceAll(t.Name(), "/", " ")
+	return model.Post{
+		Title:    "Integration article " + name,
+		Content:  "<p>" + strings.Repeat("Content written for "+name+". ", 12) + "</p>",
+		Category: "Testing",
+		Author:   "Integration",
+		Status:   "draft",
+		Tags:     []string{"Go", "MySQL"},
+	}
+}
+
+func TestPostLifecycle(t *testing.T) {
+	client := newAPIClient(t)
+
+	var created model.Post
+	client.expect(http.StatusCreated, http.MethodPost, "/article", newPost(t), &created)
+	if created.ID == 0 || created.Version != 1 || created.Category != "testing" || strings.Join(created.Tags, ",") != "go,mysql" {
+		t.Fatalf("created %+v", created)
+	}
+	path := fmt.Sprintf("/article/%d", created.ID)
+
+	var fetched model.Post
+	client.expect(http.StatusOK, http.MethodGet, path, nil, &fetched)
+	if fetched.Title != created.Title || fetched.Slug != created.Slug || fetched.WordCount == 0 {
+		t.Errorf("fetched %+v, want %+v", fetched, created)
+	}
+	client.expect(http.StatusOK, http.MethodGet, "/article/slug/"+created.Slug, nil, &fetched)
+	if fetched.ID != created.ID {
+		t.Errorf("slug lookup found post %d, want %d", fetched.ID, created.ID)
+	}
+
+	var listed []model.Post
+	client.expect(http.StatusOK, http.MethodGet, "/article?status=draft&tag=mysql&limit=100", nil, &listed)
+	found := false
+	for _, post := range listed {
+		found = found || post.ID == created.ID
+	}
+	if !found {
+		t.Errorf("post %d missing from the list", created.ID)
+	}
+
+	replacement := newPost(t)
+	replacement.Title += " revised"
+	replacement.Tags = []string{"sql"}
+	replacement.Version = created.Version
+	var updated model.Post
+	client.expect(http.StatusOK, http.MethodPut, path, replacement, &updated)
+	if updated.Version != 2 || updated.Title != replacement.Title || updated.Slug != created.Slug || strings.Join(updated.Tags, ",") != "sql" {
+		t.Errorf("updated %+v", updated)
+	}
+	if status, errorType := client.do(http.MethodPut, path, replacement, nil); status != http.StatusConflict || errorType != handlers.ErrorVersionConflict {
+		t.Errorf("stale update answered %d %s, want 409 %s", status, errorType, handlers.ErrorVersionConflict)
+	}
+
+	var patched model.Post
+	client.expect(http.StatusOK, http.MethodPatch, path, map[string]any{"status": "publish", "version": 2}, &patched)
+	if patched.Status != "publish" || patched.Version != 3 {
+		t.Errorf("patched %+v", patched)
+	}
+
+	var revisions []model.Revision
+	client.expect(http.StatusOK, http.MethodGet, path+"/revisions", nil, &revisions)
+	if len(revisions) != 2 {
+		t.Errorf("got %d revisions, want 2", len(revisions))
+	}
+
+	client.expect(http.StatusOK, http.MethodDelete, path, nil, nil)
+	client.expect(http.StatusOK, http.MethodGet, path, nil, &fetched)
+	if fetched.Status != "trash" {
+		t.Errorf("deleted post has status %q, want trash", fetched.Status)
+	}
+
+	client.expect(http.StatusOK, http.MethodDelete, path+"?permanent=true", nil, nil)
+	client.expect(http.StatusNotFound, http.MethodGet, path, nil, nil)
+	client.expect(http.StatusNotFound, http.MethodDelete, path+"?permanent=true", nil, nil)
+}
+
+func TestCreateRejectsInvalidPosts(t *testing.T) {
+	client := newAPIClient(t)
+
+	post := newPost(t)
+	post.Title = "Too short"
+	if status, errorType := client.do(http.MethodPost, "/article", post, nil); status != http.StatusUnprocessableEntity || errorType != handlers.ErrorValidationFailed {
+		t.Errorf("short title answered %d %s, want 422", status, errorType)
+	}
+	client.expect(http.StatusNotFound, http.MethodGet, "/article/999999", nil, nil)
+}
+
+func TestSameTitleGetsSuffixedSlug(t *testing.T) {
+	client := newAPIClient(t)
+
+	var first, second model.Post
+	client.expect(http.StatusCreated, http.MethodPost, "/article", newPost(t), &first)
+	other := newPost(t)
+	other.Content = "<p>" + strings.Repeat("Different words for the second article. ", 12) + "</p>"
+	client.expect(http.StatusCreated, http.MethodPost, "/article", other, &second)
+	if second.Slug != first.Slug+"-2" {
+		t.Errorf("second slug = %q, want %q", second.Slug, first.Slug+"-2")
+	}
+}
+
+func TestCategories(t *testing.T) {
+	client := newAPIClient(t)
+
+	var category model.Category
+	client.expect(http.StatusCreated, http.MethodPost, "/category", model.Category{Name: "Integration"}, &category)
+	if status, errorType := client.do(http.MethodPost, "/category", model.Category{Name: "Integration"}, nil); status != http.StatusConflict || errorType != handlers.ErrorCategoryExists {
+		t.Errorf("duplicate category answered %d %s, want 409 %s", status, errorType, handlers.ErrorCategoryExists)
+	}
+
+	post := newPost(t)
+	post.Category, post.CategoryID = "", &category.ID
+	var created model.Post
+	client.expect(http.StatusCreated, http.MethodPost, "/article", post, &created)
+	if created.Category != category.Name {
+		t.Errorf("post category = %q, want %q", created.Category, category.Name)
+	}
+
+	categoryPath := fmt.Sprintf("/category/%d", category.ID)
+	if status, errorType := client.do(http.MethodDelete, categoryPath, nil, nil); status != http.StatusConflict || errorType != handlers.ErrorCategoryInUse {
+		t.Errorf("deleting a used category answered %d %s, want 409 %s", status, errorType, handlers.ErrorCategoryInUse)
+	}
+	client.expect(http.StatusOK, http.MethodDelete, fmt.Sprintf("/article/%d?permanent=true", created.ID), nil, nil)
+	client.expect(http.StatusOK, http.MethodDelete, categoryPath, nil, nil)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationFiles holds the schema migrations, one directory per DB_DRIVER.
+//
+//go:embed database/migration
+var migrationFiles embed.FS
+
+// runMigrations brings the database at databaseURL up to the newest
+// migration for driver.
+func runMigrations(driver, databaseURL string) error {
+	source, err := iofs.New(migrationFiles, "database/migration/"+driver)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	slog.Info("database schema migrated", "version", version)
+	return nil
+}
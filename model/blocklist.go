@@ -0,0 +1,90 @@
+package model
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// BannedTerms is the blocklist titles and content are checked against, or
+// nil to allow everything.
+var BannedTerms *Blocklist
+
+// Blocklist finds banned words and phrases in text. Matching ignores case
+// and markup and only counts whole words, so a term never matches inside a
+// longer word.
+type Blocklist struct {
+	// terms maps the first word of each term to the terms starting with it,
+	// each split into words.
+	terms map[string][][]string
+}
+
+// NewBlocklist returns a Blocklist of terms. Empty terms are ignored.
+func NewBlocklist(terms []string) *Blocklist {
+	blocklist := &Blocklist{terms: map[string][][]string{}}
+	for _, term := range terms {
+		words := blocklistWords(term)
+		if len(words) > 0 {
+			blocklist.terms[words[0]] = append(blocklist.terms[words[0]], words)
+		}
+	}
+	return blocklist
+}
+
+// LoadBlocklist reads terms from the file at path, one per line. Blank lines
+// and lines starting with # are skipped.
+func LoadBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			terms = append(terms, line)
+		}
+	}
+	return terms, scanner.Err()
+}
+
+// Len is how many terms b holds.
+func (b *Blocklist) Len() int {
+	n := 0
+	for _, terms := range b.terms {
+		n += len(terms)
+	}
+	return n
+}
+
+// Find returns the banned terms in text, lowercased, in the order they first
+// appear.
+func (b *Blocklist) Find(text string) []string {
+	words := blocklistWords(plainText(text))
+	var found []string
+	seen := map[string]bool{}
+	for i, word := range words {
+		for _, term := range b.terms[word] {
+			if len(term) > len(words)-i || !slices.Equal(term, words[i:i+len(term)]) {
+				continue
+			}
+			if phrase := strings.Join(term, " "); !seen[phrase] {
+				seen[phrase] = true
+				found = append(found, phrase)
+			}
+		}
+	}
+	return found
+}
+
+// blocklistWords splits text into lowercased words of letters and digits.
+func blocklistWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
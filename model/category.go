@@ -0,0 +1,17 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Category groups posts under a managed name.
+type Category struct {
+	ID        int       `json:"id" readonly:"true"`
+	Name      string    `json:"name" binding:"required,minrunes=3,max=100" minLength:"3" maxLength:"100"`
+	CreatedAt time.Time `json:"created_at" readonly:"true"`
+}
+
+func (category *Category) Normalize() {
+	category.Name = strings.TrimSpace(category.Name)
+}
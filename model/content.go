@@ -0,0 +1,142 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	// excerptLength is roughly how many characters a generated excerpt
+	// keeps.
+	excerptLength = 160
+
+	// wordsPerMinute is the reading speed behind ReadingTimeMinutes.
+	wordsPerMinute = 200
+)
+
+var (
+	// plainTextPolicy strips all markup, for text that must not carry any.
+	plainTextPolicy = bluemonday.StrictPolicy().AddSpaceWhenStrippingTag(true)
+	// ContentPolicy sanitizes article content before it is validated and
+	// stored.
+	ContentPolicy = bluemonday.UGCPolicy()
+	// markdown renders Markdown content as CommonMark plus the GitHub
+	// Flavored Markdown tables, strikethrough and autolinks. Raw HTML in the
+	// Markdown is dropped, and the output is then sanitized with
+	// ContentPolicy like any HTML content.
+	markdown = goldmark.New(goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify))
+	// blockBreak matches the tags after which PlainText starts a new
+	// paragraph.
+	blockBreak = regexp.MustCompile(`(?i)</(p|div|h[1-6]|li|blockquote|pre|tr|table|ul|ol)>|<(br|hr)\s*/?>`)
+)
+
+// SanitizePolicy returns the HTML policy called name: "strict" strips all
+// markup, "ugc" keeps safe formatting such as links, lists and emphasis.
+func SanitizePolicy(name string) (*bluemonday.Policy, error) {
+	switch name {
+	case "strict":
+		return bluemonday.StrictPolicy(), nil
+	case "ugc":
+		return bluemonday.UGCPolicy(), nil
+	}
+	return nil, fmt.Errorf("unknown policy %q: must be strict or ugc", name)
+}
+
+// sanitizeContent removes any markup ContentPolicy does not allow, such as
+// scripts and event handler attributes.
+func sanitizeContent(content string) string {
+	return strings.TrimSpace(ContentPolicy.Sanitize(content))
+}
+
+// plainText strips all markup from text, decodes entities and collapses runs
+// of whitespace.
+func plainText(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(plainTextPolicy.Sanitize(text))), " ")
+}
+
+// PlainText returns content written in format with all markup removed, for
+// indexing and text to speech. Paragraphs, headings, list items and other
+// blocks are separated by blank lines; within each, runs of whitespace are
+// collapsed.
+func PlainText(format, content string) string {
+	var paragraphs []string
+	for _, block := range blockBreak.Split(ContentHTML(format, content), -1) {
+		if text := plainText(block); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// generateExcerpt summarizes content as its first excerptLength characters of
+// plain text, cut at a word boundary and marked with an ellipsis when
+// shortened.
+func generateExcerpt(content string) string {
+	text := plainText(content)
+	runes := []rune(text)
+	if len(runes) <= excerptLength {
+		return text
+	}
+
+	cut := string(runes[:excerptLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:!?-") + "…"
+}
+
+// prepareContent readies content written in format for storage. HTML is
+// sanitized now; Markdown is kept as written and sanitized once rendered.
+func prepareContent(format, content string) string {
+	if format == FormatMarkdown {
+		return strings.TrimSpace(content)
+	}
+	return sanitizeContent(content)
+}
+
+// ContentHTML returns content written in format as sanitized HTML.
+func ContentHTML(format, content string) string {
+	if format != FormatMarkdown {
+		return content
+	}
+	var rendered bytes.Buffer
+	if err := markdown.Convert([]byte(content), &rendered); err != nil {
+		return html.EscapeString(content)
+	}
+	return sanitizeContent(rendered.String())
+}
+
+// ContentHash is the hex SHA-256 of content's plain text, lowercased, so
+// content differing only in markup, case or spacing hashes the same.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(plainText(content))))
+	return hex.EncodeToString(sum[:])
+}
+
+// WordCount is the word count ReadingStats gives content.
+func WordCount(content string) int {
+	words, _ := ReadingStats(content)
+	return words
+}
+
+// ReadingStats counts the words of content, ignoring markup and tokens such as
+// Markdown symbols that hold no letter or digit, and estimates the minutes
+// needed to read them.
+func ReadingStats(content string) (words, minutes int) {
+	for _, token := range strings.Fields(plainText(content)) {
+		if strings.IndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+	return words, (words + wordsPerMinute - 1) / wordsPerMinute
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// xssPayloads are markup that would run script if served back as HTML.
+var xssPayloads = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=https://evil.example/x.js></SCRIPT>`,
+	`<img src=x onerror=alert(1)>`,
+	`<img src="https://example.com/a.png" onerror="alert(document.cookie)">`,
+	`<a href="javascript:alert(1)">click</a>`,
+	`<svg onload=alert(1)><circle r="1"/></svg>`,
+	`<iframe src="https://evil.example"></iframe>`,
+	`<p onclick="alert(1)" style="background:url(javascript:alert(1))">text</p>`,
+	`<scr<script>ipt>alert(1)</script>`,
+}
+
+// assertNoScript fails unless html is free of anything that runs script.
+func assertNoScript(t *testing.T, payload, html string) {
+	t.Helper()
+	lower := strings.ToLower(html)
+	for _, banned := range []string{"<script", "onerror", "onload", "onclick", "javascript:", "<iframe", "<svg", "style="} {
+		if strings.Contains(lower, banned) {
+			t.Errorf("%s sanitized to %q, which still contains %s", payload, html, banned)
+		}
+	}
+}
+
+func TestSanitizePolicyRemovesScript(t *testing.T) {
+	for _, name := range []string{"strict", "ugc"} {
+		policy, err := SanitizePolicy(name)
+		if err != nil {
+			t.Fatalf("SanitizePolicy(%q): %v", name, err)
+		}
+		for _, payload := range xssPayloads {
+			assertNoScript(t, payload, policy.Sanitize(payload))
+		}
+	}
+}
+
+func TestSanitizePolicyUnknown(t *testing.T) {
+	if _, err := SanitizePolicy("loose"); err == nil {
+		t.Error("SanitizePolicy(\"loose\") succeeded, want an error")
+	}
+}
+
+func TestSanitizePolicyFormatting(t *testing.T) {
+	const content = `<p>Some <strong>bold</strong> and a <a href="https://example.com">link</a>.</p>`
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"strict", "Some bold and a link."},
+		{"ugc", `<p>Some <strong>bold</strong> and a <a href="https://example.com" rel="nofollow">link</a>.</p>`},
+	}
+	for _, test := range tests {
+		policy, err := SanitizePolicy(test.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := policy.Sanitize(content); got != test.want {
+			t.Errorf("%s policy gave %q, want %q", test.policy, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeSanitizesContent(t *testing.T) {
+	defer func(policy *bluemonday.Policy) { ContentPolicy = policy }(ContentPolicy)
+	ContentPolicy = bluemonday.UGCPolicy()
+
+	for _, payload := range xssPayloads {
+		post := Post{Content: "<p>Intro</p>" + payload}
+		post.Normalize()
+		assertNoScript(t, payload, post.Content)
+		if !strings.Contains(post.Content, "<p>Intro</p>") {
+			t.Errorf("content %q lost its safe markup", post.Content)
+		}
+
+		content := "<p>Intro</p>" + payload
+		patch := PostPatch{Content: &content}
+		patch.Normalize()
+		assertNoScript(t, payload, *patch.Content)
+	}
+}
+
+func TestMarkdownRendersWithoutScript(t *testing.T) {
+	for _, payload := range append(xssPayloads, "[click](javascript:alert(1))") {
+		post := Post{Format: FormatMarkdown, Content: "# Title\n\n" + payload}
+		post.Normalize()
+		assertNoScript(t, payload, ContentHTML(post.Format, post.Content))
+	}
+}
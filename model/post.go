@@ -0,0 +1,447 @@
+// Package model holds the articles and categories the API serves, along
+// with the rules for cleaning up their content.
+package model
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Post is an article. The binding tags hold the validation rules, enforced
+// by the handlers on binding; minLength, maxLength and enums repeat them for the
+// OpenAPI spec.
+type Post struct {
+	ID    int    `json:"id" readonly:"true"`
+	Title string `json:"title" binding:"required,minrunes=20,max=200,no_banned_terms" minLength:"20" maxLength:"200"`
+	// Slug is derived from the title when the post is created and never
+	// changes after. It is unique among all posts, trashed ones included; a
+	// title whose slug is taken gets a -2, -3, ... suffix.
+	Slug string `json:"slug" readonly:"true"`
+	// Content is left out of list responses, which carry Excerpt instead.
+	// How it is written is given by Format.
+	Content string `json:"content,omitempty" binding:"required,minrunes=200,no_banned_terms" minLength:"200"`
+	// Format is html, the default, or markdown. HTML content is sanitized
+	// when stored; Markdown is stored as written and can be read back
+	// rendered with ?render=html.
+	Format string `json:"format" binding:"oneof=html markdown" enums:"html,markdown"`
+	// Excerpt is a plain-text summary. It is generated from Content when a
+	// write leaves it empty.
+	Excerpt string `json:"excerpt" binding:"max=300" maxLength:"300"`
+	// CoverImageURL is an http or https link to the article's hero image,
+	// or empty for none.
+	CoverImageURL string `json:"cover_image_url" binding:"max=2048,image_url" maxLength:"2048"`
+	// WordCount and ReadingTimeMinutes are derived from Content whenever a
+	// post is loaded.
+	WordCount          int `json:"word_count" readonly:"true"`
+	ReadingTimeMinutes int `json:"reading_time_minutes" readonly:"true"`
+	// Category may be left out when CategoryID is given. Written as free
+	// text, it is stored lowercased with its spacing collapsed.
+	Category string `json:"category" binding:"required_without=CategoryID,omitempty,minrunes=3,max=100" minLength:"3" maxLength:"100"`
+	// CategoryID links the post to a category entity. When set on a write,
+	// Category is filled in from that category's name.
+	CategoryID *int   `json:"category_id"`
+	Author     string `json:"author" binding:"required,max=100" minLength:"1" maxLength:"100"`
+	// AuthorID is the subject of the credentials that created the post.
+	// Authors may only change posts carrying their own subject.
+	AuthorID *string `json:"author_id" readonly:"true"`
+	Status   string  `json:"status" binding:"required,post_status" enums:"publish,draft,trash"`
+	// Featured pins the post: lists asked for featured_first show it
+	// before the others. It is false unless set.
+	Featured bool `json:"featured"`
+	// PublishAt schedules a draft: it is published once this time has
+	// passed, and a published post stays hidden until then.
+	PublishAt *time.Time `json:"publish_at"`
+	Version   int        `json:"version"`
+	ViewCount int        `json:"view_count" readonly:"true"`
+	LikeCount int        `json:"like_count" readonly:"true"`
+	// Tags are stored lowercased. On a write, leaving Tags out keeps the
+	// post's current tags and an empty list removes them.
+	Tags      []string  `json:"tags" binding:"max=20,dive,required,max=50"`
+	CreatedAt time.Time `json:"created_at" readonly:"true"`
+	UpdatedAt time.Time `json:"updated_at" readonly:"true"`
+}
+
+// ArchiveMonth is one entry of GET /article/archive.
+type ArchiveMonth struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Count int `json:"count"`
+}
+
+// Stats is returned by GET /stats. It covers posts of every status.
+type Stats struct {
+	Total      int            `json:"total"`
+	ByStatus   map[string]int `json:"by_status"`
+	ByCategory map[string]int `json:"by_category"`
+	TotalWords int            `json:"total_words"`
+	// LastUpdated is the most recently updated post, or null when there are
+	// none.
+	LastUpdated *Post `json:"last_updated"`
+}
+
+// StatusCounts is returned by GET /article/count.
+type StatusCounts struct {
+	Total   int `json:"total"`
+	Publish int `json:"publish"`
+	Draft   int `json:"draft"`
+	Trash   int `json:"trash"`
+}
+
+type Pagination struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+	// NextCursor is set when results are ordered by id and more follow, so a
+	// client can switch to cursor pagination from here.
+	NextCursor *int `json:"next_cursor,omitempty"`
+}
+
+// CursorPagination is the meta returned when paging with ?after=. NextCursor
+// is null on the last page.
+type CursorPagination struct {
+	Limit      int  `json:"limit"`
+	NextCursor *int `json:"next_cursor"`
+}
+
+// PostPatch is the body accepted by PATCH /article/:id. Nil fields are left
+// untouched; the others follow the same rules as on Post.
+type PostPatch struct {
+	Title   *string `json:"title" binding:"omitnil,minrunes=20,max=200,no_banned_terms"`
+	Content *string `json:"content" binding:"omitnil,minrunes=200,no_banned_terms"`
+	Format  *string `json:"format" binding:"omitnil,oneof=html markdown"`
+	Excerpt *string `json:"excerpt" binding:"omitnil,required,max=300"`
+	// CoverImageURL sent empty removes the cover image.
+	CoverImageURL *string    `json:"cover_image_url" binding:"omitnil,max=2048,image_url"`
+	Category      *string    `json:"category" binding:"omitnil,minrunes=3,max=100"`
+	CategoryID    *int       `json:"category_id"`
+	Author        *string    `json:"author" binding:"omitnil,required,max=100"`
+	Status        *string    `json:"status" binding:"omitnil,post_status"`
+	Featured      *bool      `json:"featured"`
+	PublishAt     *time.Time `json:"publish_at"`
+	Tags          []string   `json:"tags" binding:"omitnil,max=20,dive,required,max=50"`
+	// Version, when set, must match the stored version for the patch to
+	// apply.
+	Version *int `json:"version"`
+
+	// CurrentFormat is the stored post's format, which decides how Content
+	// is normalized when the patch does not change Format.
+	CurrentFormat string `json:"-"`
+	// CurrentExcerptGenerated reports whether the stored excerpt was
+	// generated from the content, in which case new content regenerates it.
+	CurrentExcerptGenerated bool `json:"-"`
+}
+
+// BulkRequest is the body accepted by POST /article/bulk.
+type BulkRequest struct {
+	IDs    []int  `json:"ids"`
+	Action string `json:"action" enums:"delete,publish,draft,trash"`
+}
+
+// PostCover is returned by POST /article/:id/cover.
+type PostCover struct {
+	ID            int    `json:"id"`
+	CoverImageURL string `json:"cover_image_url"`
+}
+
+// PostViews is returned by POST /article/:id/view.
+type PostViews struct {
+	ID        int `json:"id"`
+	ViewCount int `json:"view_count"`
+}
+
+// Suggestion is one title offered by GET /article/suggest.
+type Suggestion struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// PostLikes is returned by POST and DELETE /article/:id/like.
+type PostLikes struct {
+	ID        int `json:"id"`
+	LikeCount int `json:"like_count"`
+}
+
+// Revision is the state a post had before one of its edits. Version is the
+// post's version at the time, and is how the revision is addressed.
+type Revision struct {
+	Version    int       `json:"version"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Format     string    `json:"format"`
+	Excerpt    string    `json:"excerpt"`
+	Category   string    `json:"category"`
+	CategoryID *int      `json:"category_id"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BulkResult reports the outcome of a bulk action.
+type BulkResult struct {
+	Affected int   `json:"affected"`
+	NotFound []int `json:"not_found"`
+	// Rejected lists the posts left alone because their status cannot move
+	// to the requested one.
+	Rejected []int `json:"rejected"`
+}
+
+// PostFilter narrows and orders the posts returned by PostRepository.GetAll.
+// Zero values mean no filtering.
+type PostFilter struct {
+	// Statuses keeps only posts with one of these statuses.
+	Statuses []string
+	// ExcludeTrash leaves out trashed posts when Statuses is not set.
+	ExcludeTrash bool
+	CategoryID   int
+	Tag          string
+	Author       string
+	// Featured, when set, keeps only posts whose Featured matches it.
+	Featured *bool
+	// Category matches the category name regardless of case.
+	Category string
+	// From and To bound created_at, both inclusive; the zero time leaves
+	// that side open.
+	From time.Time
+	To   time.Time
+	// UpdatedSince, unless zero, keeps only posts updated after it.
+	UpdatedSince time.Time
+	Search       string
+	Sort         SortOrder
+	// After, when set, keeps only posts that come after this id in Sort's
+	// direction; Sort must then be by id.
+	After  int
+	Limit  int
+	Offset int
+	// Fields, when set, loads only these PostFields entries and the id,
+	// leaving the other fields zero.
+	Fields []string
+}
+
+// PublishedOnly reports whether f keeps only published posts.
+func (f PostFilter) PublishedOnly() bool {
+	return len(f.Statuses) == 1 && f.Statuses[0] == "publish"
+}
+
+// PostFields lists the JSON fields of Post, which list requests can narrow
+// their response to.
+var PostFields = []string{"id", "title", "slug", "content", "format", "excerpt", "cover_image_url", "word_count", "reading_time_minutes", "category", "category_id", "author", "author_id", "status", "featured", "publish_at", "version", "view_count", "like_count", "tags", "created_at", "updated_at"}
+
+// SortFields lists the fields GET /article can be sorted by.
+var SortFields = []string{"id", "title", "created_at", "updated_at", "view_count", "like_count"}
+
+// SortOrder names one of SortFields and its direction. FeaturedFirst puts
+// featured posts ahead of the rest, each group then sorted by Field.
+type SortOrder struct {
+	Field         string
+	Desc          bool
+	FeaturedFirst bool
+}
+
+// The formats Post.Content can be written in.
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// Normalize trims the text fields, sanitizes the content and fills in the
+// excerpt.
+func (post *Post) Normalize() {
+	post.Title = strings.TrimSpace(post.Title)
+	post.Format = strings.TrimSpace(post.Format)
+	if post.Format == "" {
+		post.Format = FormatHTML
+	}
+	post.Content = prepareContent(post.Format, post.Content)
+	post.CoverImageURL = strings.TrimSpace(post.CoverImageURL)
+	post.Category = NormalizeCategory(post.Category)
+	post.Author = strings.TrimSpace(post.Author)
+	post.Tags = normalizeTags(post.Tags)
+	post.fillExcerpt()
+}
+
+// Normalize applies Post's normalization to the fields present. An excerpt
+// sent empty is regenerated, which needs the new content, as is a generated
+// excerpt the patch leaves out while changing the content.
+func (patch *PostPatch) Normalize() {
+	for _, field := range []*string{patch.Title, patch.CoverImageURL, patch.Author, patch.Status} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+	if patch.Category != nil {
+		*patch.Category = NormalizeCategory(*patch.Category)
+	}
+	format := patch.CurrentFormat
+	if patch.Format != nil {
+		*patch.Format = strings.TrimSpace(*patch.Format)
+		format = *patch.Format
+	}
+	if patch.Content != nil {
+		*patch.Content = prepareContent(format, *patch.Content)
+	}
+	if patch.Excerpt != nil {
+		*patch.Excerpt = plainText(*patch.Excerpt)
+		if *patch.Excerpt == "" && patch.Content != nil {
+			*patch.Excerpt = generateExcerpt(ContentHTML(format, *patch.Content))
+		}
+	} else if patch.Content != nil && patch.CurrentExcerptGenerated {
+		excerpt := generateExcerpt(ContentHTML(format, *patch.Content))
+		patch.Excerpt = &excerpt
+	}
+	patch.Tags = normalizeTags(patch.Tags)
+}
+
+// imageExtensions are the file extensions a cover image URL may end in.
+var imageExtensions = []string{".avif", ".gif", ".jpeg", ".jpg", ".png", ".svg", ".webp"}
+
+// IsImageURL reports whether value is an absolute http or https URL. A path
+// with an extension must name an image type; one without, as image CDNs
+// often serve, is accepted.
+func IsImageURL(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return false
+	}
+	extension := strings.ToLower(path.Ext(parsed.Path))
+	return extension == "" || slices.Contains(imageExtensions, extension)
+}
+
+// ExcerptGenerated reports whether post's excerpt is the one generated from
+// its content rather than one a client wrote.
+func (post Post) ExcerptGenerated() bool {
+	return post.Excerpt == generateExcerpt(ContentHTML(post.Format, post.Content))
+}
+
+// fillExcerpt reduces Excerpt to plain text, generating it from Content when
+// the client left it empty.
+func (post *Post) fillExcerpt() {
+	post.Excerpt = plainText(post.Excerpt)
+	if post.Excerpt == "" {
+		post.Excerpt = generateExcerpt(ContentHTML(post.Format, post.Content))
+	}
+}
+
+// NormalizeCategory lowercases category and collapses its runs of whitespace.
+func NormalizeCategory(category string) string {
+	return strings.Join(strings.Fields(strings.ToLower(category)), " ")
+}
+
+// normalizeTags trims, lowercases and deduplicates tags. A nil slice stays nil
+// so writes can tell "leave the tags alone" from "remove every tag".
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+func ValidateStatus(status string) error {
+	if !IsValidStatus(status) {
+		return errors.New("Status must be either publish, draft, or trash")
+	}
+	return nil
+}
+
+func IsValidStatus(status string) bool {
+	return status == "publish" || status == "draft" || status == "trash"
+}
+
+// IsLive reports whether readers can see post at now: it is published and
+// not scheduled for later.
+func (post Post) IsLive(now time.Time) bool {
+	return post.Status == "publish" && (post.PublishAt == nil || !post.PublishAt.After(now))
+}
+
+// statusTransitions lists the statuses a post may move to from each status.
+// A trashed post has to go back to draft before it can be published again.
+var statusTransitions = map[string][]string{
+	"draft":   {"publish", "trash"},
+	"publish": {"draft", "trash"},
+	"trash":   {"draft"},
+}
+
+// CanTransition reports whether a post may move from status from to status
+// to. Keeping the same status is always allowed.
+func CanTransition(from, to string) bool {
+	return from == to || slices.Contains(statusTransitions[from], to)
+}
+
+// MaxSlugLength is how many characters a generated slug may have, suffix
+// included.
+var MaxSlugLength = 80
+
+// ReservedSlugs are never given to a post: they name routes such as
+// /article/count, so a post with one of them could not later be served at
+// /article/{slug} or linked to without ambiguity.
+var ReservedSlugs = []string{"new", "count", "random", "suggest", "archive", "export", "import", "batch", "bulk", "validate", "slug", "feed", "stats"}
+
+// IsReservedSlug reports whether slug is one of ReservedSlugs.
+func IsReservedSlug(slug string) bool {
+	return slices.Contains(ReservedSlugs, slug)
+}
+
+// Slugify lowercases title and joins its runs of letters and digits with
+// hyphens, dropping everything else. A slug longer than MaxSlugLength is cut
+// after its last whole word that fits.
+func Slugify(title string) string {
+	return truncateSlug(slugWords(title), MaxSlugLength)
+}
+
+// SuffixSlug appends -n to slug, first shortening slug as Slugify does if
+// the result would be longer than MaxSlugLength.
+func SuffixSlug(slug string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	return truncateSlug(slug, MaxSlugLength-len(suffix)) + suffix
+}
+
+// truncateSlug cuts slug to at most limit characters, at a hyphen when
+// there is one to cut at.
+func truncateSlug(slug string, limit int) string {
+	runes := []rune(slug)
+	if len(runes) <= limit {
+		return slug
+	}
+	cut := string(runes[:limit])
+	if runes[limit] != '-' {
+		if i := strings.LastIndexByte(cut, '-'); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRight(cut, "-")
+}
+
+// slugWords is Slugify without the length limit.
+func slugWords(title string) string {
+	var builder strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			pendingHyphen = false
+			continue
+		}
+		pendingHyphen = true
+	}
+	if builder.Len() == 0 {
+		return "article"
+	}
+	return builder.String()
+}
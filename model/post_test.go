@@ -0,0 +1,105 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		want     string
+	}{
+		{"Web Dev", "web dev"},
+		{"web  dev", "web dev"},
+		{"  WEB\tDEV\n", "web dev"},
+		{"web dev", "web dev"},
+		{"webdev", "webdev"},
+		{"Élan Vital", "élan vital"},
+		{"   ", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizeCategory(test.category); got != test.want {
+			t.Errorf("NormalizeCategory(%q) = %q, want %q", test.category, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"nil stays nil", nil, nil},
+		{"empty stays empty", []string{}, []string{}},
+		{"trimmed and lowercased", []string{"  Go ", "SQL"}, []string{"go", "sql"}},
+		{"duplicates after normalizing dropped", []string{"Go", "go ", " GO"}, []string{"go"}},
+		{"first occurrence order kept", []string{"b", "a", "B"}, []string{"b", "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := normalizeTags(test.tags)
+			if (got == nil) != (test.want == nil) || !slices.Equal(got, test.want) {
+				t.Errorf("normalizeTags(%q) = %#v, want %#v", test.tags, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAppliesToWrites(t *testing.T) {
+	post := Post{Category: " Web  Dev ", Tags: []string{" Go", "go"}}
+	post.Normalize()
+	if post.Category != "web dev" || !slices.Equal(post.Tags, []string{"go"}) {
+		t.Errorf("post normalized to category %q and tags %q", post.Category, post.Tags)
+	}
+
+	category := "\tWeb\n Dev"
+	patch := PostPatch{Category: &category, Tags: []string{"SQL ", " sql"}}
+	patch.Normalize()
+	if *patch.Category != "web dev" || !slices.Equal(patch.Tags, []string{"sql"}) {
+		t.Errorf("patch normalized to category %q and tags %q", *patch.Category, patch.Tags)
+	}
+
+	patch = PostPatch{}
+	patch.Normalize()
+	if patch.Category != nil || patch.Tags != nil {
+		t.Errorf("empty patch normalized to category %v and tags %#v", patch.Category, patch.Tags)
+	}
+}
+
+func TestPatchRegeneratesGeneratedExcerpt(t *testing.T) {
+	generated := Post{Format: FormatHTML, Content: "<p>The old words.</p>"}
+	generated.fillExcerpt()
+	written := Post{Format: FormatHTML, Content: "<p>The old words.</p>", Excerpt: "A summary someone wrote."}
+
+	tests := []struct {
+		name   string
+		stored Post
+		// want is the excerpt the patch sets, empty when it leaves it out.
+		want string
+	}{
+		{"generated excerpt follows the content", generated, "The new words."},
+		{"written excerpt is kept", written, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch := PostPatch{
+				Content:                 stringPointer("<p>The new words.</p>"),
+				CurrentFormat:           test.stored.Format,
+				CurrentExcerptGenerated: test.stored.ExcerptGenerated(),
+			}
+			patch.Normalize()
+			var got string
+			if patch.Excerpt != nil {
+				got = *patch.Excerpt
+			}
+			if got != test.want {
+				t.Errorf("excerpt = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func stringPointer(s string) *string { return &s }
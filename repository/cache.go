@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"backend-projects/article-api/model"
+)
+
+// PostCache is a size-bounded TTL cache of posts keyed by id. When full it
+// evicts the least recently used entry.
+type PostCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	entries map[int]*list.Element
+	recency *list.List
+
+	hits   prometheus.Counter
+	misses prometheus.Counter
+}
+
+type postCacheEntry struct {
+	post    model.Post
+	expires time.Time
+}
+
+// NewPostCache returns an empty cache whose hit and miss counters are
+// registered with registry.
+func NewPostCache(maxSize int, ttl time.Duration, registry prometheus.Registerer) *PostCache {
+	cache := &PostCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: map[int]*list.Element{},
+		recency: list.New(),
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "post_cache_hits_total",
+			Help: "Post lookups answered from the cache.",
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "post_cache_misses_total",
+			Help: "Post lookups the cache could not answer.",
+		}),
+	}
+	registry.MustRegister(cache.hits, cache.misses)
+	return cache
+}
+
+func (c *PostCache) get(id int) (model.Post, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[id]
+	if !ok || time.Now().After(element.Value.(*postCacheEntry).expires) {
+		c.misses.Inc()
+		return model.Post{}, false
+	}
+	c.hits.Inc()
+	c.recency.MoveToFront(element)
+	return element.Value.(*postCacheEntry).post, true
+}
+
+func (c *PostCache) put(post model.Post) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &postCacheEntry{post: post, expires: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[post.ID]; ok {
+		element.Value = entry
+		c.recency.MoveToFront(element)
+		return
+	}
+	c.entries[post.ID] = c.recency.PushFront(entry)
+	if c.recency.Len() > c.maxSize {
+		oldest := c.recency.Back()
+		c.recency.Remove(oldest)
+		delete(c.entries, oldest.Value.(*postCacheEntry).post.ID)
+	}
+}
+
+func (c *PostCache) remove(ids ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		if element, ok := c.entries[id]; ok {
+			c.recency.Remove(element)
+			delete(c.entries, id)
+		}
+	}
+}
+
+func (c *PostCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[int]*list.Element{}
+	c.recency.Init()
+}
+
+// CachedPostRepository serves GetByID from a PostCache and drops a post from
+// it whenever the post is written. View and like counts are not treated as
+// writes, so a cached post may lag behind on view_count and like_count until
+// it expires.
+type CachedPostRepository struct {
+	PostRepository
+	cache *PostCache
+}
+
+func NewCachedPostRepository(posts PostRepository, cache *PostCache) *CachedPostRepository {
+	return &CachedPostRepository{PostRepository: posts, cache: cache}
+}
+
+func (r *CachedPostRepository) GetByID(ctx context.Context, id int) (model.Post, error) {
+	if post, ok := r.cache.get(id); ok {
+		return post, nil
+	}
+	post, err := r.PostRepository.GetByID(ctx, id)
+	if err != nil {
+		return post, err
+	}
+	r.cache.put(post)
+	return post, nil
+}
+
+func (r *CachedPostRepository) Update(ctx context.Context, id int, post model.Post) (model.Post, error) {
+	defer r.cache.remove(id)
+	return r.PostRepository.Update(ctx, id, post)
+}
+
+func (r *CachedPostRepository) Patch(ctx context.Context, id int, patch model.PostPatch) (model.Post, error) {
+	defer r.cache.remove(id)
+	return r.PostRepository.Patch(ctx, id, patch)
+}
+
+func (r *CachedPostRepository) Restore(ctx context.Context, id, version int) (model.Post, error) {
+	defer r.cache.remove(id)
+	return r.PostRepository.Restore(ctx, id, version)
+}
+
+func (r *CachedPostRepository) Delete(ctx context.Context, id int) error {
+	defer r.cache.remove(id)
+	return r.PostRepository.Delete(ctx, id)
+}
+
+func (r *CachedPostRepository) Bulk(ctx context.Context, ids []int, action string) (model.BulkResult, []model.Post, error) {
+	defer r.cache.remove(ids...)
+	return r.PostRepository.Bulk(ctx, ids, action)
+}
+
+func (r *CachedPostRepository) PublishDue(ctx context.Context) ([]model.Post, error) {
+	published, err := r.PostRepository.PublishDue(ctx)
+	if len(published) > 0 {
+		r.cache.purge()
+	}
+	return published, err
+}
+
+// CachedCategoryRepository purges the post cache when a category is renamed,
+// since the rename rewrites the category of its posts.
+type CachedCategoryRepository struct {
+	CategoryRepository
+	cache *PostCache
+}
+
+func NewCachedCategoryRepository(categories CategoryRepository, cache *PostCache) *CachedCategoryRepository {
+	return &CachedCategoryRepository{CategoryRepository: categories, cache: cache}
+}
+
+func (r *CachedCategoryRepository) Update(ctx context.Context, id int, category model.Category) (model.Category, error) {
+	defer r.cache.purge()
+	return r.CategoryRepository.Update(ctx, id, category)
+}
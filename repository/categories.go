@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"backend-projects/article-api/model"
+)
+
+// SQLCategoryRepository is the CategoryRepository backed by the categories
+// table.
+type SQLCategoryRepository struct {
+	db *Database
+}
+
+func NewSQLCategoryRepository(db *Database) *SQLCategoryRepository {
+	return &SQLCategoryRepository{db: db}
+}
+
+func scanCategory(row rowScanner) (model.Category, error) {
+	var category model.Category
+	err := row.Scan(&category.ID, &category.Name, &category.CreatedAt)
+	if err == sql.ErrNoRows {
+		return category, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+func (r *SQLCategoryRepository) GetAll(ctx context.Context) ([]model.Category, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, created_date FROM categories ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *SQLCategoryRepository) GetByID(ctx context.Context, id int) (model.Category, error) {
+	return scanCategory(r.db.QueryRowContext(ctx, "SELECT id, name, created_date FROM categories WHERE id = ?", id))
+}
+
+func (r *SQLCategoryRepository) Create(ctx context.Context, category model.Category) (model.Category, error) {
+	id, err := r.db.dialect.insert(ctx, r.db, "INSERT INTO categories (name) VALUES (?)", category.Name)
+	if r.db.dialect.isUniqueViolation(err) {
+		return model.Category{}, ErrCategoryExists
+	}
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *SQLCategoryRepository) Update(ctx context.Context, id int, category model.Category) (model.Category, error) {
+	var updated model.Category
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		result, err := tx.ExecContext(ctx, "UPDATE categories SET name = ? WHERE id = ?", category.Name, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET category = ?, updated_date = CURRENT_TIMESTAMP WHERE category_id = ?", category.Name, id); err != nil {
+			return err
+		}
+
+		updated, err = scanCategory(tx.QueryRowContext(ctx, "SELECT id, name, created_date FROM categories WHERE id = ?", id))
+		return err
+	})
+	if r.db.dialect.isUniqueViolation(err) {
+		return model.Category{}, ErrCategoryExists
+	}
+	return updated, err
+}
+
+func (r *SQLCategoryRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", id)
+	if r.db.dialect.isForeignKeyViolation(err) {
+		return ErrCategoryInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
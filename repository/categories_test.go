@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"backend-projects/article-api/model"
+)
+
+func TestRenamingCategoryTouchesItsPosts(t *testing.T) {
+	d := &journalDriver{rows: map[string][]driver.Value{
+		"FROM categories WHERE id": {int64(7), "golang", time.Now()},
+	}}
+	repo := NewSQLCategoryRepository(newJournalRepository(t, d).db)
+
+	if _, err := repo.Update(context.Background(), 7, model.Category{Name: "golang"}); err != nil {
+		t.Fatal(err)
+	}
+	if !containsStatement(d.committed, "UPDATE posts SET category = ?, updated_date = CURRENT_TIMESTAMP") {
+		t.Errorf("committed %q, want the posts' updated_date bumped with their category", d.committed)
+	}
+}
@@ -0,0 +1,338 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryer is the subset of *sql.DB and *sql.Tx the repository needs, so the
+// same helpers work inside and outside a transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Database is a connection pool together with its SQL dialect. Queries are
+// written with ? placeholders and rebound for the dialect before they run, so
+// the repositories share one set of SQL across drivers.
+type Database struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func NewDatabase(db *sql.DB, dialect Dialect) *Database {
+	return &Database{DB: db, dialect: dialect}
+}
+
+func (db *Database) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.dialect.rebind(query), args...)
+}
+
+// QueryContext runs a SELECT a second time if the connection it ran on
+// turns out to be broken, as every pooled one is once the server has
+// restarted; database/sql then picks another connection or dials a new one.
+// Writes, locking reads, queries inside a transaction and any failure of the
+// query itself are never retried.
+func (db *Database) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query = db.dialect.rebind(query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if db.retryable(ctx, query, err) {
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+// QueryRowContext retries like QueryContext.
+func (db *Database) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	query = db.dialect.rebind(query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	if db.retryable(ctx, query, row.Err()) {
+		row = db.DB.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// retryable reports whether query failed with err only because its
+// connection was lost and is safe to run again.
+func (db *Database) retryable(ctx context.Context, query string, err error) bool {
+	return err != nil && ctx.Err() == nil && db.dialect.isConnectionError(err) && isPlainRead(query)
+}
+
+// isPlainRead reports whether query is a SELECT that takes no row locks. A
+// query starting with WITH is not one, since its CTEs may write.
+func isPlainRead(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(query, "SELECT") &&
+		!strings.Contains(query, " FOR UPDATE") && !strings.Contains(query, " FOR SHARE") &&
+		!strings.Contains(query, " LOCK IN SHARE MODE")
+}
+
+// transaction is the *sql.Tx counterpart of database.
+type transaction struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (tx *transaction) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *transaction) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+func (tx *transaction) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.dialect.rebind(query), args...)
+}
+
+// withTx runs fn inside a transaction, committing when it returns nil and
+// rolling back otherwise.
+func withTx(ctx context.Context, db *Database, fn func(tx *transaction) error) error {
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx := &transaction{Tx: sqlTx, dialect: db.dialect}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Dialect captures where the supported databases disagree on the SQL the
+// repositories issue.
+type Dialect interface {
+	// DriverName is the database/sql driver the dialect is spoken through.
+	DriverName() string
+	// rebind rewrites the ? placeholders of query into the dialect's own
+	// style.
+	rebind(query string) string
+	// insert runs an INSERT on a table with an id column and returns the id
+	// of the inserted row.
+	insert(ctx context.Context, q queryer, query string, args ...any) (int64, error)
+	// onConflictKeep is appended to an INSERT so that a row clashing with an
+	// existing one on the unique column leaves it as is while insert still
+	// reports its id.
+	onConflictKeep(column string) string
+	// extract is the SQL expression for the integer field, such as YEAR or
+	// MONTH, of the timestamp column.
+	extract(field, column string) string
+	// likeFold is a condition matching column against a LIKE pattern
+	// placeholder regardless of case, in a form an index on the column can
+	// serve for prefix patterns.
+	likeFold(column string) string
+	isUniqueViolation(err error) bool
+	// isUniqueViolationOn is isUniqueViolation for the unique index or
+	// constraint called name.
+	isUniqueViolationOn(err error, name string) bool
+	isForeignKeyViolation(err error) bool
+	// isConnectionError reports whether err means the connection broke,
+	// rather than that the statement failed.
+	isConnectionError(err error) bool
+}
+
+type MySQLDialect struct{}
+
+func (MySQLDialect) DriverName() string { return "mysql" }
+
+func (MySQLDialect) rebind(query string) string { return query }
+
+func (MySQLDialect) insert(ctx context.Context, q queryer, query string, args ...any) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (MySQLDialect) onConflictKeep(string) string {
+	// LAST_INSERT_ID(id) makes LastInsertId report the existing row.
+	return " ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)"
+}
+
+func (MySQLDialect) extract(field, column string) string {
+	return "EXTRACT(" + field + " FROM " + column + ")"
+}
+
+// likeFold relies on the column having a case-insensitive collation.
+func (MySQLDialect) likeFold(column string) string {
+	return column + " LIKE ?"
+}
+
+// MySQL server error numbers the repositories translate.
+const (
+	mysqlErrDupEntry        = 1062
+	mysqlErrRowIsReferenced = 1451
+)
+
+func (MySQLDialect) isUniqueViolation(err error) bool {
+	return isMySQLError(err, mysqlErrDupEntry)
+}
+
+// isUniqueViolationOn reads the key from the error message, the only place
+// MySQL names it: "Duplicate entry '...' for key 'table.name'", without the
+// table before 8.0.19.
+func (MySQLDialect) isUniqueViolationOn(err error, name string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDupEntry {
+		return false
+	}
+	return strings.HasSuffix(mysqlErr.Message, "'"+name+"'") || strings.HasSuffix(mysqlErr.Message, "."+name+"'")
+}
+
+func (MySQLDialect) isForeignKeyViolation(err error) bool {
+	return isMySQLError(err, mysqlErrRowIsReferenced)
+}
+
+func (MySQLDialect) isConnectionError(err error) bool {
+	return errors.Is(err, mysql.ErrInvalidConn) || isNetworkError(err)
+}
+
+// isMySQLError reports whether err is a MySQL server error with the given
+// number.
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
+type PostgresDialect struct{}
+
+func (PostgresDialect) DriverName() string { return "pgx" }
+
+// rebind numbers the placeholders $1, $2, ... in order, leaving any ? inside
+// a quoted string literal alone.
+func (PostgresDialect) rebind(query string) string {
+	var builder strings.Builder
+	inLiteral := false
+	n := 0
+	for _, r := range query {
+		if r == '\'' {
+			inLiteral = !inLiteral
+		}
+		if r == '?' && !inLiteral {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func (PostgresDialect) insert(ctx context.Context, q queryer, query string, args ...any) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+func (PostgresDialect) onConflictKeep(column string) string {
+	// DO NOTHING would return no row, so the update is a no-op that still
+	// lets RETURNING see the existing one.
+	return " ON CONFLICT (" + column + ") DO UPDATE SET " + column + " = EXCLUDED." + column
+}
+
+func (PostgresDialect) extract(field, column string) string {
+	// EXTRACT yields a numeric here, where MySQL gives an integer.
+	return "CAST(EXTRACT(" + field + " FROM " + column + ") AS INTEGER)"
+}
+
+// likeFold matches an index on LOWER(column) with text_pattern_ops.
+func (PostgresDialect) likeFold(column string) string {
+	return "LOWER(" + column + ") LIKE LOWER(?)"
+}
+
+// PostgreSQL SQLSTATE codes the repositories translate.
+const (
+	postgresUniqueViolation     = "23505"
+	postgresForeignKeyViolation = "23503"
+)
+
+func (PostgresDialect) isUniqueViolation(err error) bool {
+	return isPostgresError(err, postgresUniqueViolation)
+}
+
+func (PostgresDialect) isUniqueViolationOn(err error, name string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == postgresUniqueViolation && pgErr.ConstraintName == name
+}
+
+func (PostgresDialect) isForeignKeyViolation(err error) bool {
+	return isPostgresError(err, postgresForeignKeyViolation)
+}
+
+func (PostgresDialect) isConnectionError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// The server answered, so the connection works.
+		return false
+	}
+	return pgconn.SafeToRetry(err) || isNetworkError(err)
+}
+
+// isPostgresError reports whether err is a PostgreSQL error with the given
+// SQLSTATE code.
+func isPostgresError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// isNetworkError reports whether err comes from a connection that was
+// refused, reset or closed, whichever the driver.
+func isNetworkError(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &opErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// placeholders returns n comma-separated ? placeholders for an IN list.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// expectVersioned is expectAffected for statements guarded by a version
+// check: when no row matched it tells a missing post apart from a stale
+// version.
+func expectVersioned(ctx context.Context, q queryer, result sql.Result, id int) error {
+	err := expectAffected(result)
+	if !errors.Is(err, ErrPostNotFound) {
+		return err
+	}
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrPostNotFound
+}
+
+// expectAffected turns a statement that matched no rows into ErrPostNotFound.
+func expectAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
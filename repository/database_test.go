@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// flakyDriver is a database/sql driver whose statements fail with a dropped
+// connection failures times before they succeed, counting every attempt.
+// The failure is a network error rather than driver.ErrBadConn, which
+// database/sql already retries itself before the repository sees it.
+type flakyDriver struct {
+	failures int
+	attempts int
+}
+
+func (d *flakyDriver) Connect(context.Context) (driver.Conn, error) { return flakyConn{d}, nil }
+
+func (d *flakyDriver) Driver() driver.Driver { return nil }
+
+func (d *flakyDriver) attempt() error {
+	d.attempts++
+	if d.failures > 0 {
+		d.failures--
+		return &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}
+	return nil
+}
+
+type flakyConn struct{ d *flakyDriver }
+
+func (flakyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (flakyConn) Close() error { return nil }
+
+func (flakyConn) Begin() (driver.Tx, error) { return flakyTx{}, nil }
+
+func (c flakyConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.attempt(); err != nil {
+		return nil, err
+	}
+	return emptyRows{}, nil
+}
+
+func (c flakyConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.attempt(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type flakyTx struct{}
+
+func (flakyTx) Commit() error   { return nil }
+func (flakyTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return []string{"n"} }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+func newFlakyDatabase(t *testing.T, failures int) (*Database, *flakyDriver) {
+	t.Helper()
+	d := &flakyDriver{failures: failures}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return NewDatabase(db, MySQLDialect{}), d
+}
+
+func TestQueryContextRetriesPlainReads(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		retried bool
+	}{
+		{"select", "SELECT id FROM posts", true},
+		{"lowercase select after whitespace", "\n\tselect id from posts", true},
+		{"select for update", "SELECT id FROM posts WHERE id = ? FOR UPDATE", false},
+		{"select for share", "SELECT id FROM posts WHERE id = ? FOR SHARE", false},
+		{"select lock in share mode", "SELECT id FROM posts WHERE id = ? LOCK IN SHARE MODE", false},
+		{"common table expression", "WITH ids AS (SELECT id FROM posts) SELECT id FROM ids", false},
+		{"insert returning", "INSERT INTO tags (name) VALUES (?) RETURNING id", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, d := newFlakyDatabase(t, 1)
+
+			rows, err := db.QueryContext(context.Background(), test.query, 1)
+			if err == nil {
+				rows.Close()
+			}
+
+			wantAttempts := 1
+			if test.retried {
+				wantAttempts = 2
+			}
+			if d.attempts != wantAttempts {
+				t.Errorf("attempts = %d, want %d", d.attempts, wantAttempts)
+			}
+			if test.retried != (err == nil) {
+				t.Errorf("err = %v, want retried %t", err, test.retried)
+			}
+		})
+	}
+}
+
+func TestQueryRowContextRetriesOnce(t *testing.T) {
+	db, d := newFlakyDatabase(t, 2)
+
+	err := db.QueryRowContext(context.Background(), "SELECT id FROM posts WHERE id = ?", 1).Scan(new(int))
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("err = %v, want the connection error of the second attempt", err)
+	}
+	if d.attempts != 2 {
+		t.Errorf("attempts = %d, want 2", d.attempts)
+	}
+}
+
+func TestExecContextIsNotRetried(t *testing.T) {
+	db, d := newFlakyDatabase(t, 1)
+
+	if _, err := db.ExecContext(context.Background(), "UPDATE posts SET views = views + 1 WHERE id = ?", 1); err == nil {
+		t.Error("err = nil, want the connection error")
+	}
+	if d.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", d.attempts)
+	}
+}
+
+func TestTransactionQueryIsNotRetried(t *testing.T) {
+	db, d := newFlakyDatabase(t, 1)
+
+	err := withTx(context.Background(), db, func(tx *transaction) error {
+		rows, err := tx.QueryContext(context.Background(), "SELECT id FROM posts")
+		if err == nil {
+			rows.Close()
+		}
+		return err
+	})
+	if err == nil {
+		t.Error("err = nil, want the connection error")
+	}
+	if d.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", d.attempts)
+	}
+}
+
+func TestQueryContextIsNotRetriedOnceCancelled(t *testing.T) {
+	db, d := newFlakyDatabase(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := db.QueryContext(ctx, "SELECT id FROM posts"); err == nil {
+		t.Error("err = nil, want an error")
+	}
+	if d.attempts > 1 {
+		t.Errorf("attempts = %d, want at most 1", d.attempts)
+	}
+}
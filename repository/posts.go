@@ -0,0 +1,1271 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"backend-projects/article-api/model"
+)
+
+// postColumns lists the columns read by scanPost, in scan order.
+const postColumns = "id, title, slug, content, format, excerpt, cover_image_url, category, category_id, author, author_id, status, featured, publish_date, version, view_count, like_count, created_date, updated_date"
+
+// sortColumns maps each of model.SortFields to its column.
+var sortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"created_at": "created_date",
+	"updated_at": "updated_date",
+	"view_count": "view_count",
+	"like_count": "like_count",
+}
+
+// SQLPostRepository is the PostRepository backed by the posts table.
+type SQLPostRepository struct {
+	db *Database
+}
+
+func NewSQLPostRepository(db *Database) *SQLPostRepository {
+	return &SQLPostRepository{db: db}
+}
+
+// postFieldColumns maps each of model.PostFields to the column it is read
+// from. The word counts are derived from content, and tags are loaded from
+// post_tags by id.
+var postFieldColumns = map[string]string{
+	"id":                   "id",
+	"title":                "title",
+	"slug":                 "slug",
+	"content":              "content",
+	"format":               "format",
+	"excerpt":              "excerpt",
+	"cover_image_url":      "cover_image_url",
+	"word_count":           "content",
+	"reading_time_minutes": "content",
+	"category":             "category",
+	"category_id":          "category_id",
+	"author":               "author",
+	"author_id":            "author_id",
+	"status":               "status",
+	"featured":             "featured",
+	"publish_at":           "publish_date",
+	"version":              "version",
+	"view_count":           "view_count",
+	"like_count":           "like_count",
+	"tags":                 "id",
+	"created_at":           "created_date",
+	"updated_at":           "updated_date",
+}
+
+// fieldColumns returns the columns, in postColumns order, that the post
+// fields need. The id is always included.
+func fieldColumns(fields []string) []string {
+	needed := map[string]bool{"id": true}
+	for _, field := range fields {
+		needed[postFieldColumns[field]] = true
+	}
+	var columns []string
+	for _, column := range strings.Split(postColumns, ", ") {
+		if needed[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func scanPost(row rowScanner) (model.Post, error) {
+	return scanPostColumns(row, strings.Split(postColumns, ", "))
+}
+
+// scanPostColumns scans a row holding columns, a subset of postColumns, into
+// a post whose other fields stay zero.
+func scanPostColumns(row rowScanner, columns []string) (model.Post, error) {
+	var post model.Post
+	targets := map[string]any{
+		"id":              &post.ID,
+		"title":           &post.Title,
+		"slug":            &post.Slug,
+		"content":         &post.Content,
+		"format":          &post.Format,
+		"excerpt":         &post.Excerpt,
+		"cover_image_url": &post.CoverImageURL,
+		"category":        &post.Category,
+		"category_id":     &post.CategoryID,
+		"author":          &post.Author,
+		"author_id":       &post.AuthorID,
+		"status":          &post.Status,
+		"featured":        &post.Featured,
+		"publish_date":    &post.PublishAt,
+		"version":         &post.Version,
+		"view_count":      &post.ViewCount,
+		"like_count":      &post.LikeCount,
+		"created_date":    &post.CreatedAt,
+		"updated_date":    &post.UpdatedAt,
+	}
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		dest[i] = targets[column]
+	}
+	err := row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		return post, ErrPostNotFound
+	}
+	post.WordCount, post.ReadingTimeMinutes = model.ReadingStats(post.Content)
+	return post, err
+}
+
+func (r *SQLPostRepository) GetAll(ctx context.Context, filter model.PostFilter) ([]model.Post, int, error) {
+	where, args := whereClause(filter)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	columns := strings.Split(postColumns, ", ")
+	if filter.Fields != nil {
+		columns = fieldColumns(filter.Fields)
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM posts"+where+" ORDER BY "+orderByClause(filter.Sort)+" LIMIT ? OFFSET ?", append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posts := []model.Post{}
+	for rows.Next() {
+		post, err := scanPostColumns(rows, columns)
+		if err != nil {
+			return nil, 0, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if filter.Fields == nil || slices.Contains(filter.Fields, "tags") {
+		if err := loadTags(ctx, r.db, posts); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return posts, total, nil
+}
+
+// exportBatchSize is how many posts Export reads per query.
+const exportBatchSize = 100
+
+// Export reads posts a batch at a time, carrying on after the last id seen,
+// so memory use does not grow with the table and no query is left open while
+// each runs.
+func (r *SQLPostRepository) Export(ctx context.Context, status string, each func(model.Post) error) error {
+	where := " WHERE id > ?"
+	if status != "" {
+		where += " AND status = ?"
+	}
+	lastID := 0
+	for {
+		args := []any{lastID}
+		if status != "" {
+			args = append(args, status)
+		}
+		rows, err := r.db.QueryContext(ctx, "SELECT "+postColumns+" FROM posts"+where+" ORDER BY id LIMIT ?", append(args, exportBatchSize)...)
+		if err != nil {
+			return err
+		}
+		posts := []model.Post{}
+		for rows.Next() {
+			post, err := scanPost(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			posts = append(posts, post)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+		if err := loadTags(ctx, r.db, posts); err != nil {
+			return err
+		}
+
+		for _, post := range posts {
+			if err := each(post); err != nil {
+				return err
+			}
+		}
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+func (r *SQLPostRepository) GetByIDs(ctx context.Context, ids []int, fields []string) ([]model.Post, error) {
+	if len(ids) == 0 {
+		return []model.Post{}, nil
+	}
+	columns := strings.Split(postColumns, ", ")
+	if fields != nil {
+		columns = fieldColumns(fields)
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM posts WHERE id IN ("+placeholders(len(ids))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int]model.Post{}
+	for rows.Next() {
+		post, err := scanPostColumns(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		byID[post.ID] = post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts := []model.Post{}
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+	if fields == nil || slices.Contains(fields, "tags") {
+		if err := loadTags(ctx, r.db, posts); err != nil {
+			return nil, err
+		}
+	}
+	return posts, nil
+}
+
+// Suggest runs the prefix matches as their own query so that they can be
+// found through the title index; the contains matches that may follow need a
+// scan, but only when there are too few prefix matches.
+func (r *SQLPostRepository) Suggest(ctx context.Context, query string, limit int) ([]model.Suggestion, error) {
+	where, args := whereClause(model.PostFilter{Statuses: []string{"publish"}})
+	where += " AND " + r.db.dialect.likeFold("title")
+	contains := likePattern(query)
+	prefix := strings.TrimPrefix(contains, "%")
+
+	suggestions := []model.Suggestion{}
+	for _, condition := range []struct {
+		sql  string
+		args []any
+	}{
+		{where, append(slices.Clip(args), prefix)},
+		{where + " AND NOT " + r.db.dialect.likeFold("title"), append(slices.Clip(args), contains, prefix)},
+	} {
+		rows, err := r.db.QueryContext(ctx, "SELECT id, title, slug FROM posts"+condition.sql+" ORDER BY created_date DESC, id DESC LIMIT ?", append(condition.args, limit-len(suggestions))...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var suggestion model.Suggestion
+			if err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Slug); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(suggestions) >= limit {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// whereClause builds the WHERE clause, with its arguments, selecting the posts
+// that match filter.
+func whereClause(filter model.PostFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN ("+placeholders(len(filter.Statuses))+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(filter.Statuses) == 0 && filter.ExcludeTrash {
+		conditions = append(conditions, "status <> 'trash'")
+	}
+	if slices.Contains(filter.Statuses, "publish") {
+		// A post published ahead of its publish_at is not live yet.
+		conditions = append(conditions, "(status <> 'publish' OR publish_date IS NULL OR publish_date <= CURRENT_TIMESTAMP)")
+	}
+	if filter.CategoryID > 0 {
+		conditions = append(conditions, "category_id = ?")
+		args = append(args, filter.CategoryID)
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "author = ?")
+		args = append(args, filter.Author)
+	}
+	if filter.Featured != nil {
+		conditions = append(conditions, "featured = ?")
+		args = append(args, *filter.Featured)
+	}
+	if filter.Category != "" {
+		// On MySQL the column's collation also makes this ignore accents.
+		conditions = append(conditions, "LOWER(category) = LOWER(?)")
+		args = append(args, filter.Category)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = posts.id AND t.name = ?)")
+		args = append(args, filter.Tag)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_date >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_date <= ?")
+		args = append(args, filter.To)
+	}
+	if !filter.UpdatedSince.IsZero() {
+		conditions = append(conditions, "updated_date > ?")
+		args = append(args, filter.UpdatedSince)
+	}
+	if filter.Search != "" {
+		pattern := likePattern(filter.Search)
+		conditions = append(conditions, "(LOWER(title) LIKE LOWER(?) OR LOWER(content) LIKE LOWER(?))")
+		args = append(args, pattern, pattern)
+	}
+	if filter.After > 0 {
+		if filter.Sort.Desc {
+			conditions = append(conditions, "id < ?")
+		} else {
+			conditions = append(conditions, "id > ?")
+		}
+		args = append(args, filter.After)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// orderByClause maps order onto its column, breaking ties by id so pages are
+// stable.
+func orderByClause(order model.SortOrder) string {
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+
+	var featured string
+	if order.FeaturedFirst {
+		featured = "featured DESC, "
+	}
+	column, ok := sortColumns[order.Field]
+	if !ok || column == "id" {
+		return featured + "id " + direction
+	}
+	return featured + column + " " + direction + ", id " + direction
+}
+
+// likePattern wraps term in % wildcards, escaping any LIKE metacharacters it
+// already contains so they match literally.
+func likePattern(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return "%" + replacer.Replace(term) + "%"
+}
+
+func (r *SQLPostRepository) CountByStatus(ctx context.Context, filter model.PostFilter) (map[string]int, error) {
+	where, args := whereClause(filter)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM posts"+where+" GROUP BY status", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *SQLPostRepository) GetByID(ctx context.Context, id int) (model.Post, error) {
+	return getPostByID(ctx, r.db, id)
+}
+
+func getPostByID(ctx context.Context, q queryer, id int) (model.Post, error) {
+	return getPost(ctx, q, "id = ?", id)
+}
+
+func (r *SQLPostRepository) GetBySlug(ctx context.Context, slug string) (model.Post, error) {
+	return getPost(ctx, r.db, "slug = ?", slug)
+}
+
+// getPost loads the single post matching condition, along with its tags.
+func getPost(ctx context.Context, q queryer, condition string, arg any) (model.Post, error) {
+	post, err := scanPost(q.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE "+condition, arg))
+	if err != nil {
+		return post, err
+	}
+
+	posts := []model.Post{post}
+	err = loadTags(ctx, q, posts)
+	return posts[0], err
+}
+
+// loadTags fills in Tags for every post in posts with a single query.
+func loadTags(ctx context.Context, q queryer, posts []model.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	index := map[int]int{}
+	args := make([]any, len(posts))
+	for i := range posts {
+		posts[i].Tags = []string{}
+		index[posts[i].ID] = i
+		args[i] = posts[i].ID
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT pt.post_id, t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id IN ("+placeholders(len(posts))+") ORDER BY t.name", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return err
+		}
+		post := &posts[index[postID]]
+		post.Tags = append(post.Tags, name)
+	}
+	return rows.Err()
+}
+
+// setTags replaces the tags of post id with tags, creating any tag that does
+// not exist yet. A nil tags leaves them untouched.
+func setTags(ctx context.Context, tx *transaction, id int, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = ?", id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		tagID, err := tx.dialect.insert(ctx, tx, "INSERT INTO tags (name) VALUES (?)"+tx.dialect.onConflictKeep("name"), tag)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO post_tags (post_id, tag_id) VALUES (?, ?)", id, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The unique indexes on posts whose violations writes report by name.
+const (
+	postsSlugUnique = "posts_slug_unique"
+	// postsTitleUnique is not created by the migrations; a deployment that
+	// wants titles to be unique adds a unique index of this name.
+	postsTitleUnique = "posts_title_unique"
+)
+
+// postWriteError reports a write that broke one of the unique indexes on
+// posts as the error naming it, and returns any other err as is.
+func postWriteError(dialect Dialect, err error) error {
+	switch {
+	case dialect.isUniqueViolationOn(err, postsSlugUnique):
+		return ErrDuplicateSlug
+	case dialect.isUniqueViolationOn(err, postsTitleUnique):
+		return ErrDuplicateTitle
+	default:
+		return err
+	}
+}
+
+// slugAttempts is how many times a create is tried when a concurrent one
+// takes the slug it picked between the check and the insert.
+const slugAttempts = 3
+
+func (r *SQLPostRepository) Create(ctx context.Context, post model.Post) (model.Post, error) {
+	var created model.Post
+	var err error
+	for attempt := 0; attempt < slugAttempts; attempt++ {
+		err = withTx(ctx, r.db, func(tx *transaction) error {
+			var err error
+			created, err = createPost(ctx, tx, post)
+			return err
+		})
+		if !r.db.dialect.isUniqueViolationOn(err, postsSlugUnique) {
+			break
+		}
+	}
+	if err != nil {
+		return model.Post{}, postWriteError(r.db.dialect, err)
+	}
+	return created, nil
+}
+
+// CreateBatch issues one INSERT per post rather than a multi-row one, since
+// each post needs its own id back for its slug check and tags, and neither
+// driver reliably reports the ids of a multi-row INSERT.
+func (r *SQLPostRepository) CreateBatch(ctx context.Context, posts []model.Post) ([]model.Post, error) {
+	var created []model.Post
+	var err error
+	for attempt := 0; attempt < slugAttempts; attempt++ {
+		slugTaken := false
+		err = withTx(ctx, r.db, func(tx *transaction) error {
+			created = make([]model.Post, 0, len(posts))
+			for i, post := range posts {
+				stored, err := createPost(ctx, tx, post)
+				if err != nil {
+					slugTaken = r.db.dialect.isUniqueViolationOn(err, postsSlugUnique)
+					return &BatchError{Index: i, Err: postWriteError(r.db.dialect, err)}
+				}
+				created = append(created, stored)
+			}
+			return nil
+		})
+		if !slugTaken {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// createPost inserts post within tx and returns it as stored.
+func createPost(ctx context.Context, tx *transaction, post model.Post) (model.Post, error) {
+	slug, err := uniqueSlug(ctx, tx, post.Title)
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	id, err := tx.dialect.insert(ctx, tx, "INSERT INTO posts (title, slug, content, format, excerpt, word_count, content_hash, cover_image_url, category, category_id, author, author_id, status, featured, publish_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", post.Title, slug, post.Content, post.Format, post.Excerpt, model.WordCount(post.Content), model.ContentHash(post.Content), post.CoverImageURL, post.Category, post.CategoryID, post.Author, post.AuthorID, post.Status, post.Featured, post.PublishAt)
+	if err != nil {
+		return model.Post{}, err
+	}
+	if err := setTags(ctx, tx, int(id), post.Tags); err != nil {
+		return model.Post{}, err
+	}
+	if err := markScheduled(ctx, tx, id); err != nil {
+		return model.Post{}, err
+	}
+
+	return getPostByID(ctx, tx, int(id))
+}
+
+func (r *SQLPostRepository) Slug(ctx context.Context, title string) (string, error) {
+	return uniqueSlug(ctx, r.db, title)
+}
+
+// uniqueSlug derives a slug from title, appending -2, -3, ... until it is
+// neither reserved nor taken by an existing post. Posts of every status
+// count, trashed ones included, so a slug is only free again once its post
+// is permanently deleted.
+func uniqueSlug(ctx context.Context, q queryer, title string) (string, error) {
+	base := model.Slugify(title)
+	slug := base
+	for counter := 2; ; counter++ {
+		var exists bool
+		if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE slug = ?)", slug).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists && !model.IsReservedSlug(slug) {
+			return slug, nil
+		}
+		slug = model.SuffixSlug(base, counter)
+	}
+}
+
+func (r *SQLPostRepository) Update(ctx context.Context, id int, post model.Post) (model.Post, error) {
+	var updated model.Post
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		if err := checkTransition(ctx, tx, id, post.Status); err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, tx, id); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "UPDATE posts SET title = ?, content = ?, format = ?, excerpt = ?, word_count = ?, content_hash = ?, cover_image_url = ?, category = ?, category_id = ?, author = ?, status = ?, featured = ?, publish_date = ?, version = version + 1, updated_date = CURRENT_TIMESTAMP WHERE id = ? AND version = ?", post.Title, post.Content, post.Format, post.Excerpt, model.WordCount(post.Content), model.ContentHash(post.Content), post.CoverImageURL, post.Category, post.CategoryID, post.Author, post.Status, post.Featured, post.PublishAt, id, post.Version)
+		if err != nil {
+			return err
+		}
+		if err := expectVersioned(ctx, tx, result, id); err != nil {
+			return err
+		}
+		if err := setTags(ctx, tx, id, post.Tags); err != nil {
+			return err
+		}
+		if err := markScheduled(ctx, tx, id); err != nil {
+			return err
+		}
+
+		updated, err = getPostByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return model.Post{}, postWriteError(r.db.dialect, err)
+	}
+	return updated, nil
+}
+
+func (r *SQLPostRepository) Patch(ctx context.Context, id int, patch model.PostPatch) (model.Post, error) {
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"title", patch.Title},
+		{"content", patch.Content},
+		{"format", patch.Format},
+		{"excerpt", patch.Excerpt},
+		{"cover_image_url", patch.CoverImageURL},
+		{"category", patch.Category},
+		{"author", patch.Author},
+		{"status", patch.Status},
+	}
+
+	var assignments []string
+	var args []any
+	for _, field := range fields {
+		if field.value != nil {
+			assignments = append(assignments, field.column+" = ?")
+			args = append(args, *field.value)
+		}
+	}
+	if patch.Content != nil {
+		assignments = append(assignments, "word_count = ?", "content_hash = ?")
+		args = append(args, model.WordCount(*patch.Content), model.ContentHash(*patch.Content))
+	}
+	if patch.CategoryID != nil {
+		assignments = append(assignments, "category_id = ?")
+		args = append(args, *patch.CategoryID)
+	} else if patch.Category != nil {
+		// A free-text category unlinks the post, as it does on create and
+		// update, so category and category_id never disagree.
+		assignments = append(assignments, "category_id = NULL")
+	}
+	if patch.Featured != nil {
+		assignments = append(assignments, "featured = ?")
+		args = append(args, *patch.Featured)
+	}
+	if patch.PublishAt != nil {
+		assignments = append(assignments, "publish_date = ?")
+		args = append(args, *patch.PublishAt)
+	}
+	assignments = append(assignments, "version = version + 1", "updated_date = CURRENT_TIMESTAMP")
+
+	where := " WHERE id = ?"
+	args = append(args, id)
+	if patch.Version != nil {
+		where += " AND version = ?"
+		args = append(args, *patch.Version)
+	}
+
+	var patched model.Post
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		if patch.Status != nil {
+			if err := checkTransition(ctx, tx, id, *patch.Status); err != nil {
+				return err
+			}
+		}
+		if err := recordRevision(ctx, tx, id); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "UPDATE posts SET "+strings.Join(assignments, ", ")+where, args...)
+		if err != nil {
+			return err
+		}
+		if err := expectVersioned(ctx, tx, result, id); err != nil {
+			return err
+		}
+		if err := setTags(ctx, tx, id, patch.Tags); err != nil {
+			return err
+		}
+		if err := markScheduled(ctx, tx, id); err != nil {
+			return err
+		}
+
+		patched, err = getPostByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return model.Post{}, postWriteError(r.db.dialect, err)
+	}
+	return patched, nil
+}
+
+// markScheduled sets the scheduled flag of the posts with ids: whether they
+// are published for a time still ahead, which PublishDue announces once it
+// has passed. Writes call it after they have stored the status and
+// publish_date.
+func markScheduled(ctx context.Context, tx *transaction, ids ...any) error {
+	_, err := tx.ExecContext(ctx, "UPDATE posts SET scheduled = (status = 'publish' AND publish_date IS NOT NULL AND publish_date > CURRENT_TIMESTAMP) WHERE id IN ("+placeholders(len(ids))+")", ids...)
+	return err
+}
+
+// revisionColumns are the post_revisions columns copied from posts.
+const revisionColumns = "version, title, content, format, excerpt, category, category_id, status"
+
+// recordRevision copies the post's current state into post_revisions. The row
+// is locked first, so the revision matches the version the caller's update
+// replaces. A missing post records nothing and is left for the update to
+// report.
+func recordRevision(ctx context.Context, tx *transaction, id int) error {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM posts WHERE id = ? FOR UPDATE", id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO post_revisions (post_id, "+revisionColumns+") SELECT id, "+revisionColumns+" FROM posts WHERE id = ?", id)
+	return err
+}
+
+// checkTransition locks the post and returns a *TransitionError when its
+// status may not change to status. A missing post passes, leaving the write
+// to report it.
+func checkTransition(ctx context.Context, tx *transaction, id int, status string) error {
+	var current string
+	err := tx.QueryRowContext(ctx, "SELECT status FROM posts WHERE id = ? FOR UPDATE", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !model.CanTransition(current, status) {
+		return &TransitionError{From: current, To: status}
+	}
+	return nil
+}
+
+func (r *SQLPostRepository) Revisions(ctx context.Context, id int) ([]model.Revision, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+revisionColumns+", created_date FROM post_revisions WHERE post_id = ? ORDER BY version DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		var revision model.Revision
+		if err := rows.Scan(&revision.Version, &revision.Title, &revision.Content, &revision.Format, &revision.Excerpt, &revision.Category, &revision.CategoryID, &revision.Status, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id = ?)", id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrPostNotFound
+		}
+	}
+	return revisions, nil
+}
+
+func (r *SQLPostRepository) Restore(ctx context.Context, id, version int) (model.Post, error) {
+	var restored model.Post
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		var revision model.Revision
+		err := tx.QueryRowContext(ctx, "SELECT title, content, format, excerpt, category, category_id, status FROM post_revisions WHERE post_id = ? AND version = ?", id, version).Scan(&revision.Title, &revision.Content, &revision.Format, &revision.Excerpt, &revision.Category, &revision.CategoryID, &revision.Status)
+		if err == sql.ErrNoRows {
+			if _, err := getPostByID(ctx, tx, id); err != nil {
+				return err
+			}
+			return ErrRevisionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkTransition(ctx, tx, id, revision.Status); err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, tx, id); err != nil {
+			return err
+		}
+		// A category deleted since the revision was taken leaves the post
+		// with just the category name, as posts had before categories.
+		result, err := tx.ExecContext(ctx, "UPDATE posts SET title = ?, content = ?, format = ?, excerpt = ?, word_count = ?, content_hash = ?, category = ?, category_id = (SELECT id FROM categories WHERE id = ?), status = ?, version = version + 1, updated_date = CURRENT_TIMESTAMP WHERE id = ?", revision.Title, revision.Content, revision.Format, revision.Excerpt, model.WordCount(revision.Content), model.ContentHash(revision.Content), revision.Category, revision.CategoryID, revision.Status, id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		if err := markScheduled(ctx, tx, id); err != nil {
+			return err
+		}
+
+		restored, err = getPostByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return model.Post{}, postWriteError(r.db.dialect, err)
+	}
+	return restored, nil
+}
+
+func (r *SQLPostRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (r *SQLPostRepository) Bulk(ctx context.Context, ids []int, action string) (model.BulkResult, []model.Post, error) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	in := " WHERE id IN (" + placeholders(len(ids)) + ")"
+
+	result := model.BulkResult{NotFound: []int{}, Rejected: []int{}}
+	published := []model.Post{}
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		rows, err := tx.QueryContext(ctx, "SELECT id, status FROM posts"+in+" FOR UPDATE", args...)
+		if err != nil {
+			return err
+		}
+		statuses := map[int]string{}
+		for rows.Next() {
+			var id int
+			var status string
+			if err := rows.Scan(&id, &status); err != nil {
+				rows.Close()
+				return err
+			}
+			statuses[id] = status
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		args = args[:0]
+		for _, id := range ids {
+			status, found := statuses[id]
+			switch {
+			case !found:
+				result.NotFound = append(result.NotFound, id)
+			case action != "delete" && !model.CanTransition(status, action):
+				result.Rejected = append(result.Rejected, id)
+			default:
+				args = append(args, id)
+			}
+		}
+		if len(args) == 0 {
+			return nil
+		}
+		in = " WHERE id IN (" + placeholders(len(args)) + ")"
+
+		var execResult sql.Result
+		if action == "delete" {
+			execResult, err = tx.ExecContext(ctx, "DELETE FROM posts"+in, args...)
+		} else {
+			// The rows are already locked, so the revisions match the states
+			// being replaced.
+			if _, err := tx.ExecContext(ctx, "INSERT INTO post_revisions (post_id, "+revisionColumns+") SELECT id, "+revisionColumns+" FROM posts"+in, args...); err != nil {
+				return err
+			}
+			execResult, err = tx.ExecContext(ctx, "UPDATE posts SET status = ?, version = version + 1, updated_date = CURRENT_TIMESTAMP"+in, append([]any{action}, args...)...)
+		}
+		if err != nil {
+			return err
+		}
+
+		affected, err := execResult.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result.Affected = int(affected)
+		if action == "delete" {
+			return nil
+		}
+		if err := markScheduled(ctx, tx, args...); err != nil {
+			return err
+		}
+
+		if action != "publish" {
+			return nil
+		}
+		newlyPublished := []any{}
+		for _, id := range args {
+			if statuses[id.(int)] != "publish" {
+				newlyPublished = append(newlyPublished, id)
+			}
+		}
+		published, err = postsByID(ctx, tx, newlyPublished)
+		return err
+	})
+	return result, published, err
+}
+
+// postsByID loads the posts whose ids are in ids, along with their tags.
+func postsByID(ctx context.Context, q queryer, ids []any) ([]model.Post, error) {
+	posts := []model.Post{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	rows, err := q.QueryContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id IN ("+placeholders(len(ids))+") ORDER BY id", ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, loadTags(ctx, q, posts)
+}
+
+func (r *SQLPostRepository) Related(ctx context.Context, post model.Post, limit int) ([]model.Post, error) {
+	where, args := whereClause(model.PostFilter{Statuses: []string{"publish"}})
+
+	// Tags are matched by name, since those are what post already carries.
+	related := "category = ?"
+	args = append(args, post.ID, post.Category)
+	if len(post.Tags) > 0 {
+		related += " OR EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = posts.id AND t.name IN (" + placeholders(len(post.Tags)) + "))"
+		for _, tag := range post.Tags {
+			args = append(args, tag)
+		}
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+postColumns+" FROM posts"+where+" AND id <> ? AND ("+related+") ORDER BY created_date DESC, id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []model.Post{}
+	for rows.Next() {
+		relatedPost, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, relatedPost)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, loadTags(ctx, r.db, posts)
+}
+
+func (r *SQLPostRepository) Adjacent(ctx context.Context, post model.Post, newer bool) (model.Post, error) {
+	where, args := whereClause(model.PostFilter{Statuses: []string{"publish"}})
+	comparison, order := ">", "ASC"
+	if !newer {
+		comparison, order = "<", "DESC"
+	}
+	args = append(args, post.CreatedAt, post.CreatedAt, post.ID)
+
+	adjacent, err := scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts"+where+" AND (created_date "+comparison+" ? OR (created_date = ? AND id "+comparison+" ?)) ORDER BY created_date "+order+", id "+order+" LIMIT 1", args...))
+	if err != nil {
+		return adjacent, err
+	}
+
+	posts := []model.Post{adjacent}
+	err = loadTags(ctx, r.db, posts)
+	return posts[0], err
+}
+
+func (r *SQLPostRepository) Stats(ctx context.Context) (model.Stats, error) {
+	byStatus, err := r.CountByStatus(ctx, model.PostFilter{})
+	if err != nil {
+		return model.Stats{}, err
+	}
+	stats := model.Stats{ByStatus: byStatus, ByCategory: map[string]int{}}
+	for _, count := range byStatus {
+		stats.Total += count
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT category, COUNT(*) FROM posts GROUP BY category")
+	if err != nil {
+		return model.Stats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category string
+		var count int
+		if err := rows.Scan(&category, &count); err != nil {
+			return model.Stats{}, err
+		}
+		stats.ByCategory[category] = count
+	}
+	if err := rows.Err(); err != nil {
+		return model.Stats{}, err
+	}
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(word_count), 0) FROM posts").Scan(&stats.TotalWords); err != nil {
+		return model.Stats{}, err
+	}
+
+	last, err := scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts ORDER BY updated_date DESC, id DESC LIMIT 1"))
+	if errors.Is(err, ErrPostNotFound) {
+		return stats, nil
+	}
+	if err != nil {
+		return model.Stats{}, err
+	}
+	posts := []model.Post{last}
+	if err := loadTags(ctx, r.db, posts); err != nil {
+		return model.Stats{}, err
+	}
+	stats.LastUpdated = &posts[0]
+	return stats, nil
+}
+
+// CountWords fills in word_count for posts written before the column
+// existed, a batch at a time, until none are left.
+func (r *SQLPostRepository) CountWords(ctx context.Context) error {
+	for {
+		rows, err := r.db.QueryContext(ctx, "SELECT id, content FROM posts WHERE word_count IS NULL LIMIT 100")
+		if err != nil {
+			return err
+		}
+		counts := map[int]int{}
+		for rows.Next() {
+			var id int
+			var content string
+			if err := rows.Scan(&id, &content); err != nil {
+				rows.Close()
+				return err
+			}
+			counts[id] = model.WordCount(content)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(counts) == 0 {
+			return nil
+		}
+
+		for id, words := range counts {
+			if _, err := r.db.ExecContext(ctx, "UPDATE posts SET word_count = ? WHERE id = ?", words, id); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// HashContent fills in content_hash for posts written before the column
+// existed, a batch at a time, until none are left.
+func (r *SQLPostRepository) HashContent(ctx context.Context) error {
+	for {
+		rows, err := r.db.QueryContext(ctx, "SELECT id, content FROM posts WHERE content_hash IS NULL LIMIT 100")
+		if err != nil {
+			return err
+		}
+		hashes := map[int]string{}
+		for rows.Next() {
+			var id int
+			var content string
+			if err := rows.Scan(&id, &content); err != nil {
+				rows.Close()
+				return err
+			}
+			hashes[id] = model.ContentHash(content)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+
+		for id, hash := range hashes {
+			if _, err := r.db.ExecContext(ctx, "UPDATE posts SET content_hash = ? WHERE id = ?", hash, id); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// FindDuplicate looks posts up by content_hash. Posts not yet hashed are
+// missed until HashContent reaches them.
+func (r *SQLPostRepository) FindDuplicate(ctx context.Context, content string) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM posts WHERE content_hash = ? AND status <> 'trash' ORDER BY id LIMIT 1", model.ContentHash(content)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrPostNotFound
+	}
+	return id, err
+}
+
+func (r *SQLPostRepository) Archive(ctx context.Context) ([]model.ArchiveMonth, error) {
+	where, args := whereClause(model.PostFilter{Statuses: []string{"publish"}})
+	year := r.db.dialect.extract("YEAR", "created_date")
+	month := r.db.dialect.extract("MONTH", "created_date")
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+year+" AS archive_year, "+month+" AS archive_month, COUNT(*) FROM posts"+where+" GROUP BY archive_year, archive_month ORDER BY archive_year DESC, archive_month DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	months := []model.ArchiveMonth{}
+	for rows.Next() {
+		var month model.ArchiveMonth
+		if err := rows.Scan(&month.Year, &month.Month, &month.Count); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
+
+// Random picks a random id between the lowest and highest published ids and
+// returns the first published post from there, wrapping around to the
+// lowest. Both lookups are index seeks, unlike ORDER BY RAND(), at the cost
+// of favouring posts that follow gaps in the ids.
+func (r *SQLPostRepository) Random(ctx context.Context) (model.Post, error) {
+	where, args := whereClause(model.PostFilter{Statuses: []string{"publish"}})
+
+	var lowest, highest sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, "SELECT MIN(id), MAX(id) FROM posts"+where, args...).Scan(&lowest, &highest); err != nil {
+		return model.Post{}, err
+	}
+	if !lowest.Valid {
+		return model.Post{}, ErrPostNotFound
+	}
+	pick := lowest.Int64 + rand.Int64N(highest.Int64-lowest.Int64+1)
+
+	post, err := scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts"+where+" AND id >= ? ORDER BY id LIMIT 1", append(args, pick)...))
+	if errors.Is(err, ErrPostNotFound) {
+		// Every post from pick on was unpublished after the range was read.
+		post, err = scanPost(r.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts"+where+" ORDER BY id LIMIT 1", args...))
+	}
+	if err != nil {
+		return post, err
+	}
+
+	posts := []model.Post{post}
+	err = loadTags(ctx, r.db, posts)
+	return posts[0], err
+}
+
+func (r *SQLPostRepository) AddView(ctx context.Context, id int) (int, error) {
+	var viewCount int
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		// The increment happens in the database, so concurrent views are
+		// never lost; the row lock it takes keeps the read below consistent.
+		result, err := tx.ExecContext(ctx, "UPDATE posts SET view_count = view_count + 1 WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, "SELECT view_count FROM posts WHERE id = ?", id).Scan(&viewCount)
+	})
+	return viewCount, err
+}
+
+func (r *SQLPostRepository) Like(ctx context.Context, id int, liked bool) (int, error) {
+	update := "UPDATE posts SET like_count = like_count + 1 WHERE id = ?"
+	if !liked {
+		// The row still matches at zero, so an unlike there is not taken
+		// for a missing post.
+		update = "UPDATE posts SET like_count = CASE WHEN like_count > 0 THEN like_count - 1 ELSE 0 END WHERE id = ?"
+	}
+
+	var likeCount int
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		result, err := tx.ExecContext(ctx, update, id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, "SELECT like_count FROM posts WHERE id = ?", id).Scan(&likeCount)
+	})
+	return likeCount, err
+}
+
+func (r *SQLPostRepository) PublishDue(ctx context.Context) ([]model.Post, error) {
+	var published []model.Post
+	err := withTx(ctx, r.db, func(tx *transaction) error {
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM posts WHERE (status = 'draft' OR scheduled) AND publish_date <= CURRENT_TIMESTAMP FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		ids := []any{}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			published = []model.Post{}
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE posts SET status = 'publish', scheduled = FALSE, version = version + 1, updated_date = CURRENT_TIMESTAMP WHERE id IN ("+placeholders(len(ids))+")", ids...); err != nil {
+			return err
+		}
+		published, err = postsByID(ctx, tx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return published, nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"backend-projects/article-api/model"
+)
+
+// journalDriver is a database/sql driver that keeps the statements a
+// transaction executes apart until it commits, so a test can tell what
+// would have been stored and what was thrown away. Queries answer with the
+// row of a rows key they contain, or with no rows, and statements containing
+// failOn fail.
+type journalDriver struct {
+	rows      map[string][]driver.Value
+	failOn    string
+	pending   []string
+	committed []string
+	discarded []string
+	commits   int
+	rollbacks int
+}
+
+func (d *journalDriver) Connect(context.Context) (driver.Conn, error) { return journalConn{d}, nil }
+
+func (d *journalDriver) Driver() driver.Driver { return nil }
+
+var errJournalFailure = errors.New("statement failed")
+
+type journalConn struct{ d *journalDriver }
+
+func (journalConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (journalConn) Close() error { return nil }
+
+func (c journalConn) Begin() (driver.Tx, error) {
+	c.d.pending = nil
+	return journalTx{c.d}, nil
+}
+
+func (c journalConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	for match, row := range c.d.rows {
+		if strings.Contains(query, match) {
+			return &rowOf{values: row}, nil
+		}
+	}
+	return &rowOf{}, nil
+}
+
+func (c journalConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if c.d.failOn != "" && strings.Contains(query, c.d.failOn) {
+		return nil, errJournalFailure
+	}
+	c.d.pending = append(c.d.pending, query)
+	return journalResult{}, nil
+}
+
+type journalTx struct{ d *journalDriver }
+
+func (tx journalTx) Commit() error {
+	tx.d.commits++
+	tx.d.committed = append(tx.d.committed, tx.d.pending...)
+	tx.d.pending = nil
+	return nil
+}
+
+func (tx journalTx) Rollback() error {
+	tx.d.rollbacks++
+	tx.d.discarded = append(tx.d.discarded, tx.d.pending...)
+	tx.d.pending = nil
+	return nil
+}
+
+type journalResult struct{}
+
+func (journalResult) LastInsertId() (int64, error) { return 1, nil }
+func (journalResult) RowsAffected() (int64, error) { return 1, nil }
+
+// rowOf yields values as a single row, or no row when values is nil.
+type rowOf struct {
+	values []driver.Value
+	done   bool
+}
+
+func (r *rowOf) Columns() []string {
+	columns := make([]string, len(r.values))
+	for i := range columns {
+		columns[i] = "c"
+	}
+	return columns
+}
+
+func (r *rowOf) Close() error { return nil }
+
+func (r *rowOf) Next(dest []driver.Value) error {
+	if r.values == nil || r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+func newJournalRepository(t *testing.T, d *journalDriver) *SQLPostRepository {
+	t.Helper()
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return NewSQLPostRepository(NewDatabase(db, MySQLDialect{}))
+}
+
+func TestWithTx(t *testing.T) {
+	d := &journalDriver{}
+	repo := newJournalRepository(t, d)
+
+	err := withTx(context.Background(), repo.db, func(tx *transaction) error {
+		_, err := tx.ExecContext(context.Background(), "INSERT INTO tags (name) VALUES (?)", "kept")
+		return err
+	})
+	if err != nil || d.commits != 1 || len(d.committed) != 1 {
+		t.Fatalf("successful transaction: err %v, %d commits, committed %q", err, d.commits, d.committed)
+	}
+
+	fnErr := errors.New("second step failed")
+	err = withTx(context.Background(), repo.db, func(tx *transaction) error {
+		if _, err := tx.ExecContext(context.Background(), "INSERT INTO tags (name) VALUES (?)", "dropped"); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("err = %v, want %v", err, fnErr)
+	}
+	if d.commits != 1 || d.rollbacks != 1 || len(d.committed) != 1 {
+		t.Errorf("failed transaction: %d commits, %d rollbacks, committed %q", d.commits, d.rollbacks, d.committed)
+	}
+}
+
+func TestCreateRollsBackOnFailure(t *testing.T) {
+	d := &journalDriver{
+		rows:   map[string][]driver.Value{"SELECT EXISTS": {false}},
+		failOn: "INSERT INTO post_tags",
+	}
+	repo := newJournalRepository(t, d)
+
+	post := model.Post{Title: "A title long enough to pass", Content: "content", Status: "draft", Tags: []string{"go"}}
+	if _, err := repo.Create(context.Background(), post); !errors.Is(err, errJournalFailure) {
+		t.Fatalf("err = %v, want %v", err, errJournalFailure)
+	}
+	if d.commits != 0 || d.rollbacks != 1 {
+		t.Errorf("%d commits and %d rollbacks, want 0 and 1", d.commits, d.rollbacks)
+	}
+	if len(d.committed) != 0 {
+		t.Errorf("committed %q, want nothing", d.committed)
+	}
+	if !containsStatement(d.discarded, "INSERT INTO posts") {
+		t.Errorf("discarded %q, want the post's insert among them", d.discarded)
+	}
+}
+
+func TestUpdateRollsBackOnFailure(t *testing.T) {
+	d := &journalDriver{
+		rows: map[string][]driver.Value{
+			"SELECT status FROM posts":  {"draft"},
+			"SELECT version FROM posts": {int64(3)},
+		},
+		failOn: "UPDATE posts",
+	}
+	repo := newJournalRepository(t, d)
+
+	post := model.Post{Title: "A title long enough to pass", Content: "content", Status: "publish", Version: 3}
+	if _, err := repo.Update(context.Background(), 1, post); !errors.Is(err, errJournalFailure) {
+		t.Fatalf("err = %v, want %v", err, errJournalFailure)
+	}
+	if d.commits != 0 || d.rollbacks != 1 {
+		t.Errorf("%d commits and %d rollbacks, want 0 and 1", d.commits, d.rollbacks)
+	}
+	if len(d.committed) != 0 || !containsStatement(d.discarded, "INSERT INTO post_revisions") {
+		t.Errorf("committed %q and discarded %q, want the revision discarded", d.committed, d.discarded)
+	}
+}
+
+// containsStatement reports whether any of statements contains text.
+func containsStatement(statements []string, text string) bool {
+	return slices.ContainsFunc(statements, func(statement string) bool { return strings.Contains(statement, text) })
+}
@@ -0,0 +1,152 @@
+// Package repository stores posts and categories in MySQL or PostgreSQL,
+// optionally behind an in-memory cache.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"backend-projects/article-api/model"
+)
+
+// PostRepository is the storage used by the post handlers. Methods return
+// ErrPostNotFound when the requested post does not exist.
+type PostRepository interface {
+	// GetAll returns one page of posts matching filter along with the total
+	// number of matching posts.
+	GetAll(ctx context.Context, filter model.PostFilter) ([]model.Post, int, error)
+	GetByID(ctx context.Context, id int) (model.Post, error)
+	GetBySlug(ctx context.Context, slug string) (model.Post, error)
+	// Slug returns the slug Create would give a post titled title now.
+	Slug(ctx context.Context, title string) (string, error)
+	// Create stores post under a freshly generated unique slug. A post
+	// keeps its slug for good, whatever its title or status later become,
+	// so restoring a trashed post never clashes with one created since.
+	Create(ctx context.Context, post model.Post) (model.Post, error)
+	// Export calls each with every post, or every post stored with status
+	// when it is set, in id order. It stops at the first error each returns.
+	Export(ctx context.Context, status string, each func(model.Post) error) error
+	// FindDuplicate returns the id of a post, other than a trashed one, whose
+	// content matches content once markup, case and spacing are ignored.
+	FindDuplicate(ctx context.Context, content string) (int, error)
+	// GetByIDs returns the posts with ids, in the order of ids, skipping ids
+	// that match no post. A non-nil fields narrows what is loaded as
+	// PostFilter.Fields does.
+	GetByIDs(ctx context.Context, ids []int, fields []string) ([]model.Post, error)
+	// Suggest returns up to limit live published posts whose title contains
+	// query, ignoring case: titles starting with it first, then the others,
+	// newest first within each.
+	Suggest(ctx context.Context, query string, limit int) ([]model.Suggestion, error)
+	// CreateBatch stores posts like Create, in one transaction: either all
+	// of them are stored or, with a *BatchError, none are.
+	CreateBatch(ctx context.Context, posts []model.Post) ([]model.Post, error)
+	// Update replaces the post provided post.Version matches the stored
+	// version, returning ErrVersionConflict otherwise.
+	Update(ctx context.Context, id int, post model.Post) (model.Post, error)
+	// Patch applies the non-nil fields of patch, checking patch.Version
+	// when it is set.
+	Patch(ctx context.Context, id int, patch model.PostPatch) (model.Post, error)
+	// CountByStatus returns how many posts matching filter have each status.
+	// Statuses without posts are omitted.
+	CountByStatus(ctx context.Context, filter model.PostFilter) (map[string]int, error)
+	// Delete removes the post permanently.
+	Delete(ctx context.Context, id int) error
+	// Bulk applies action to every post in ids within one transaction.
+	// "delete" removes the posts permanently; any other action is the status
+	// to move them to. It also returns the posts that a publish action moved
+	// from another status.
+	Bulk(ctx context.Context, ids []int, action string) (model.BulkResult, []model.Post, error)
+	// Related returns up to limit other published posts that share post's
+	// category or any of its tags, newest first.
+	Related(ctx context.Context, post model.Post, limit int) ([]model.Post, error)
+	// Adjacent returns the published post created just after post, or just
+	// before it when newer is false, ordering ties by id. It returns
+	// ErrPostNotFound when there is none.
+	Adjacent(ctx context.Context, post model.Post, newer bool) (model.Post, error)
+	// Stats summarizes every post.
+	Stats(ctx context.Context) (model.Stats, error)
+	// Archive counts the published posts created in each month, newest
+	// month first.
+	Archive(ctx context.Context) ([]model.ArchiveMonth, error)
+	// Random returns a published post picked at random, or ErrPostNotFound
+	// when nothing is published.
+	Random(ctx context.Context) (model.Post, error)
+	// AddView counts one more view of the post and returns the new total.
+	AddView(ctx context.Context, id int) (int, error)
+	// Like adds one like to the post, or takes one away when liked is false
+	// without going below zero, and returns the new total.
+	Like(ctx context.Context, id int, liked bool) (int, error)
+	// PublishDue publishes every draft whose publish_at has passed and
+	// returns them, along with the posts published for a later time that
+	// has now come, which it returns once.
+	PublishDue(ctx context.Context) ([]model.Post, error)
+	// Revisions returns the post's earlier states, newest first. Update,
+	// Patch, Bulk and Restore each record one per post they change.
+	Revisions(ctx context.Context, id int) ([]model.Revision, error)
+	// Restore puts the title, content, excerpt, category and status of the
+	// revision carrying version back on the post, recording the state it
+	// replaces as a new revision.
+	Restore(ctx context.Context, id, version int) (model.Post, error)
+}
+
+// CategoryRepository is the storage used by the category handlers. Methods
+// return ErrCategoryNotFound when the requested category does not exist.
+type CategoryRepository interface {
+	GetAll(ctx context.Context) ([]model.Category, error)
+	GetByID(ctx context.Context, id int) (model.Category, error)
+	Create(ctx context.Context, category model.Category) (model.Category, error)
+	// Update renames the category and the category name stored on its posts.
+	Update(ctx context.Context, id int, category model.Category) (model.Category, error)
+	// Delete removes the category, returning ErrCategoryInUse while posts
+	// still reference it.
+	Delete(ctx context.Context, id int) error
+}
+
+var (
+	ErrPostNotFound = errors.New("post not found")
+	// ErrVersionConflict is returned by writes whose expected version no
+	// longer matches the stored one.
+	ErrVersionConflict = errors.New("post was modified by someone else")
+	// ErrDuplicateTitle is returned by writes that would give two posts the
+	// same title, which only a posts_title_unique index forbids.
+	ErrDuplicateTitle = errors.New("an article with this title already exists")
+	// ErrDuplicateSlug is returned by creates whose slug kept being taken by
+	// concurrent ones.
+	ErrDuplicateSlug = errors.New("another article took this slug at the same time")
+	// ErrRevisionNotFound is returned when a post has no revision with the
+	// requested version.
+	ErrRevisionNotFound = errors.New("revision not found")
+
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryInUse    = errors.New("category is still used by posts")
+	// ErrCategoryExists is returned by writes that would give two categories
+	// the same name.
+	ErrCategoryExists = errors.New("a category with this name already exists")
+)
+
+// TransitionError is returned by writes that would move a post between two
+// statuses model.CanTransition does not allow.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("post status cannot change from %s to %s", e.From, e.To)
+}
+
+// BatchError is returned by CreateBatch when the post at Index could not be
+// stored.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("post %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalCoverStore keeps covers as files in dir, which the server exposes
+// under baseURL.
+type LocalCoverStore struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalCoverStore(dir, baseURL string) *LocalCoverStore {
+	return &LocalCoverStore{dir: dir, baseURL: baseURL}
+}
+
+func (s *LocalCoverStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Writing to a temporary file first means a failed upload never leaves
+	// a partial image behind under key.
+	file, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(s.dir, key)); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *LocalCoverStore) Delete(ctx context.Context, imageURL string) error {
+	key, ok := strings.CutPrefix(imageURL, s.baseURL+"/")
+	if !ok || key != filepath.Base(key) {
+		return nil
+	}
+	err := os.Remove(filepath.Join(s.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3CoverStore keeps covers as objects under prefix in an S3 bucket, served
+// from baseURL.
+type S3CoverStore struct {
+	client  *s3.Client
+	bucket  string
+	prefix  string
+	baseURL string
+}
+
+// NewS3CoverStore connects to bucket with the credentials and region found
+// in the standard AWS environment variables and config files. An empty
+// baseURL means the bucket's own virtual-hosted URL.
+func NewS3CoverStore(ctx context.Context, bucket, prefix, baseURL string) (*S3CoverStore, error) {
+	if bucket == "" {
+		return nil, errors.New("COVER_S3_BUCKET is required")
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, cfg.Region)
+	}
+	return &S3CoverStore{client: s3.NewFromConfig(cfg), bucket: bucket, prefix: prefix, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (s *S3CoverStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.prefix + key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + s.prefix + key, nil
+}
+
+func (s *S3CoverStore) Delete(ctx context.Context, imageURL string) error {
+	key, ok := strings.CutPrefix(imageURL, s.baseURL+"/")
+	if !ok || !strings.HasPrefix(key, s.prefix) {
+		return nil
+	}
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
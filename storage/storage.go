@@ -0,0 +1,17 @@
+// Package storage keeps uploaded cover images on local disk or in S3.
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// CoverStore keeps uploaded cover images.
+type CoverStore interface {
+	// Put stores the image read from body under key and returns the URL it
+	// is served from.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
+	// Delete removes the image served from imageURL. URLs the store did not
+	// hand out, such as links to images hosted elsewhere, are left alone.
+	Delete(ctx context.Context, imageURL string) error
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"backend-projects/article-api/handlers"
+)
+
+// commit and buildTime identify the build. Release builds set them with
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// and otherwise they fall back to the VCS details Go records in the binary.
+var (
+	commit    string
+	buildTime string
+)
+
+// buildInfo reports what GET /version returns.
+func buildInfo() handlers.BuildInfo {
+	info := handlers.BuildInfo{Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			case setting.Key == "vcs.modified" && setting.Value == "true":
+				info.Modified = true
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,129 @@
+// Package webhook tells other systems about article events by POSTing them
+// to configured URLs.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// EventHeader names the event a delivery reports.
+	EventHeader = "X-Webhook-Event"
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of
+	// the request body, keyed with the shared secret, so receivers can check
+	// a delivery came from this API.
+	SignatureHeader = "X-Webhook-Signature"
+
+	// EventPublished is sent, with the article as the body, when an
+	// article goes live: its status becomes publish, or its scheduled
+	// publish_at comes.
+	EventPublished = "article.published"
+
+	// firstRetryDelay is how long a failed delivery waits before its first
+	// retry; the wait doubles after every further failure.
+	firstRetryDelay = time.Second
+)
+
+// Notifier delivers events to a fixed set of URLs. Deliveries run in the
+// background and a failed one is retried, so notifying never holds up or
+// fails the request that caused it; deliveries that still fail are logged.
+type Notifier struct {
+	urls    []string
+	secret  []byte
+	client  *http.Client
+	retries int
+}
+
+// New returns a Notifier posting to urls, each of which must be an absolute
+// http or https URL. Every attempt is bounded by timeout, and a failed
+// delivery is retried up to retries times.
+func New(urls []string, secret []byte, timeout time.Duration, retries int) (*Notifier, error) {
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", rawURL)
+		}
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid webhook timeout %s", timeout)
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("invalid webhook retries %d", retries)
+	}
+	return &Notifier{urls: urls, secret: secret, client: &http.Client{Timeout: timeout}, retries: retries}, nil
+}
+
+// Notify sends payload as JSON to every URL, labelled with event. It returns
+// at once. A nil Notifier, or one without URLs, does nothing.
+func (n *Notifier) Notify(event string, payload any) {
+	if n == nil || len(n.urls) == 0 {
+		return
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("encoding webhook payload", "event", event, "error", err)
+		return
+	}
+	for _, target := range n.urls {
+		go n.deliver(target, event, body)
+	}
+}
+
+// deliver posts body to target until it succeeds or the retries run out.
+func (n *Notifier) deliver(target, event string, body []byte) {
+	delay := firstRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := n.send(target, event, body)
+		if err == nil {
+			return
+		}
+		if attempt > n.retries {
+			slog.Warn("webhook delivery failed", "url", target, "event", event, "attempts", attempt, "error", err)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send makes one delivery attempt. Any response outside 2xx is a failure.
+func (n *Notifier) send(target, event string, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, event)
+	request.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// Draining the body lets the connection be reused.
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", response.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret, as sent in
+// SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}